@@ -3,11 +3,11 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"path"
 	"path/filepath"
 	"syscall"
 	"time"
@@ -15,15 +15,41 @@ import (
 	"connect-text-bot/bot"
 	"connect-text-bot/internal/botconfig_parser"
 	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/client"
 	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/dedup"
+	"connect-text-bot/internal/health"
 	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/metrics"
+	"connect-text-bot/internal/transcript"
 	"connect-text-bot/internal/us"
+	"connect-text-bot/internal/worker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gopkg.in/fsnotify.v1"
 )
 
+// сколько файлы конфигов должны не меняться, чтобы изменения применились
+const RELOAD_DEBOUNCE = 500 * time.Millisecond
+
 func main() {
+	// подкоманды
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(validate(os.Args[2:]))
+		case "graph":
+			os.Exit(graph(os.Args[2:]))
+		case "simulate":
+			os.Exit(simulate(os.Args[2:]))
+		case "test":
+			os.Exit(dialogTest(os.Args[2:]))
+		case "transcript":
+			os.Exit(exportTranscript(os.Args[2:]))
+		}
+	}
+
 	var (
 		cnf = &config.Conf{}
 
@@ -37,31 +63,89 @@ func main() {
 
 	config.GetConfig(*configFile, cnf)
 	cnf.BotConfig = *botConfig
+	cnf.SetLineDefaults()
 
 	logFile := logger.InitLogger(*debug, loggerConfig)
 	defer logFile.Close()
 	logger.Info("Application starting...")
 
 	if *debug {
-		logger.Debug("Config:", cnf)
+		logger.Debug("Config:", cnf.Redacted())
 	} else {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	cache := database.ConnectInMemoryCache()
-	menus := botconfig_parser.InitLevels(cnf.BotConfig)
+	client.Configure(cnf.ConnectServer.Retry, cnf.ConnectServer.CircuitBreaker, cnf.ConnectServer.RateLimit)
+	cache := database.ConnectStateStore(cnf.StateStore)
+	menus := botconfig_parser.InitMenuSet(cnf.BotConfigs())
+	pool := worker.New(cnf.Workers)
+	filter := dedup.New(cnf.Dedup)
+
+	archive, err := transcript.New(cnf.Transcript)
+	if err != nil {
+		logger.Crit("Error while open transcript archive:", err)
+	}
 
-	app := gin.Default()
+	// журнал запросов без gin.Default, его журнал пишет строку запроса вместе с токеном хука
+	app := gin.New()
+	app.Use(gin.Recovery())
+	accessLog := logger.GinText(io.MultiWriter(os.Stdout, logFile))
+	if logger.IsJSON() {
+		// журнал запросов тоже в json, в файл он попадает вместе с остальными логами
+		accessLog = logger.Gin()
+	}
+	if err := app.SetTrustedProxies(cnf.Server.TrustedProxies); err != nil {
+		logger.Crit("Error while parse trusted_proxies:", err)
+	}
 	app.Use(
 		config.Inject("cnf", cnf),
-		database.InjectInMemoryCache("cache", cache),
-		botconfig_parser.InjectLevels("menus", menus),
-		gin.LoggerWithWriter(logFile),
+		database.InjectStateStore("cache", cache),
+		worker.Inject("pool", pool),
+		dedup.Inject("dedup", filter),
+		transcript.Inject("transcript", archive),
+		accessLog,
 		us.Inject(cnf.UsServer, cnf.Connect.Login, cnf.Connect.Password),
 		us.InjectMTOM(cnf.UsServer, cnf.Connect.Login, cnf.Connect.Password),
 	)
 
-	bot.InitHooks(app, cnf)
+	// Следим за изменениями конфигов бота.
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Crit(err)
+	}
+	defer watcher.Close()
+
+	// следим за каталогами меню всех линий, в том числе добавленных при перечитывании config.yml
+	watchMenus := func() {
+		directories, err := menus.Dirs()
+		if err != nil {
+			logger.Warning(err)
+			return
+		}
+		for _, dir := range directories {
+			if err := watcher.Add(dir); err != nil {
+				logger.Warning("Не удалось найти:", dir)
+			}
+		}
+	}
+	reloader := bot.NewReloader(*configFile, *botConfig, cnf, menus, archive, watchMenus)
+
+	bot.InitHooks(app, cnf, menus, archive)
+	bot.InitAdmin(app, cnf, reloader)
+
+	// метрики для Prometheus
+	app.GET("/metrics", metrics.Handler())
+
+	// проверки работоспособности
+	probe := client.New(uuid.Nil, cnf.ConnectServer.Addr, cnf.Connect.Login, cnf.Connect.Password, cnf.GeneralSettings, cnf.SpecID)
+	checker := health.New(cnf.Health)
+	checker.Add("hooks", false, bot.HooksReady)
+	checker.Add("menu", false, menus.Loaded)
+	checker.Add("connect", true, probe.Ping)
+	checker.Add("us", true, us.Ping(cnf.UsServer, cnf.Connect.Login, cnf.Connect.Password))
+
+	app.GET("/healthz", checker.Healthz)
+	app.GET("/readyz", checker.Readyz)
 
 	srv := &http.Server{
 		Addr:    cnf.Server.Listen,
@@ -74,55 +158,43 @@ func main() {
 		}
 	}()
 
-	// Следим за изменениями конфига бота.
-	watcher, err := fsnotify.NewWatcher()
+	configPath, err := filepath.Abs(*configFile)
 	if err != nil {
 		logger.Crit(err)
 	}
-	defer watcher.Close()
-	go func() {
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return
-				}
-				logger.Event(event)
-				if event.Op&fsnotify.Create == fsnotify.Create || event.Op&fsnotify.Rename == fsnotify.Rename {
-					if event.Name != "" {
-						if err := watcher.Add(event.Name); err != nil {
-							logger.Warning("Не удалось найти:", event.Name)
-							_ = watcher.Remove(event.Name)
-						}
-					}
-					logger.Warning("При таких изменениях конфигурации рекомендуется перезагрузить бота!")
-				}
-				if event.Op&fsnotify.Write == fsnotify.Write {
-					err = menus.UpdateLevels(cnf.BotConfig)
-					if err != nil {
-						logger.Warning("Не корректный конфиг бота!", err)
+
+	// изменения применяются когда файлы перестали меняться, чтобы не читать недописанный файл
+	applyChanges := func(changed map[string]fsnotify.Op) {
+		files := make([]string, 0, len(changed))
+		for name, op := range changed {
+			// в новых каталогах тоже могут быть файлы меню
+			if op&(fsnotify.Create|fsnotify.Rename) != 0 {
+				if info, err := os.Stat(name); err == nil && info.IsDir() {
+					if err := watcher.Add(name); err != nil {
+						logger.Warning("Не удалось найти:", name)
 					}
 				}
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					return
-				}
-				log.Println("error:", err)
 			}
+			files = append(files, name)
 		}
-	}()
+		reloader.Apply(files)
+	}
 
-	// ищем все директории в папке
-	var directories []string
-	err = filepath.Walk(path.Dir(cnf.BotConfig), func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			directories = append(directories, path)
+	go bot.CollectChanges(watcher.Events, RELOAD_DEBOUNCE, applyChanges)
+	go func() {
+		for err := range watcher.Errors {
+			log.Println("error:", err)
 		}
-		return nil
-	})
+	}()
+
+	// ищем все директории в папках с конфигами меню
+	directories, err := menus.Dirs()
+	if err != nil {
+		logger.Crit(err)
+	}
+
+	// и за каталогом config.yml
+	directories = append(directories, filepath.Dir(configPath))
 
 	// устанавливаем триггер на все папки
 	for _, dir := range directories {
@@ -134,7 +206,7 @@ func main() {
 	logger.Info("Application started")
 
 	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
 
 	quit := make(chan int)
 
@@ -142,18 +214,40 @@ func main() {
 		for {
 			sig := <-signals
 			switch sig {
-			// kill -SIGHUP XXXX
+			// kill -SIGHUP XXXX - перечитать config.yml и конфиги меню
+			case syscall.SIGHUP:
+				logger.Info("Catch SIGHUP! Reloading configs...")
+				reloader.Reload()
+			// kill XXXX, systemctl stop
 			// kill -SIGINT XXXX or Ctrl+c
-			case syscall.SIGHUP, syscall.SIGINT:
+			case syscall.SIGTERM, syscall.SIGINT:
 				logger.Info("Catch OS signal! Exiting...")
 
 				bot.DestroyHooks()
 
+				// горутина не завершается, поэтому контексты отменяем явно, а не через defer
 				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-
 				if err := srv.Shutdown(ctx); err != nil {
-					log.Fatal(logger.CritColor("App forced to shutdown:", err))
+					// принятые сообщения все равно дорабатываем и закрываем хранилища
+					logger.Warning("App forced to shutdown:", err)
+				}
+				cancel()
+
+				// дожидаемся обработки уже принятых сообщений
+				drainCtx, cancelDrain := context.WithTimeout(context.Background(), cnf.Workers.DrainTimeout)
+				if err := pool.Shutdown(drainCtx); err != nil {
+					logger.Warning("Not all messages were processed before shutdown:", err)
+				}
+				cancelDrain()
+
+				filter.Close()
+
+				if err := archive.Close(); err != nil {
+					logger.Warning("Error while close transcript archive", err)
+				}
+
+				if err := cache.Close(); err != nil {
+					logger.Warning("Error while close state store", err)
 				}
 
 				logger.Info("Application stopped correctly!")
@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"connect-text-bot/internal/cache"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/transcript"
+	"connect-text-bot/internal/us"
+	"connect-text-bot/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hooklift/gowsdl/soap"
+)
+
+const adminUri = "/admin"
+
+type (
+	// краткие данные о диалоге пользователя
+	session struct {
+		UserID        uuid.UUID `json:"user_id"`
+		LineID        uuid.UUID `json:"line_id"`
+		CurrentState  string    `json:"curr_state"`
+		PreviousState string    `json:"prev_state"`
+	}
+
+	// запрос на перевод пользователя в меню
+	gotoRequest struct {
+		// id меню
+		Menu string `json:"menu" binding:"required"`
+		// отправить пользователю сообщение и клавиатуру меню
+		Send bool `json:"send"`
+	}
+)
+
+// InitAdmin - зарегистрировать методы для просмотра и исправления состояний пользователей
+// и перечитывания конфигов
+func InitAdmin(app *gin.Engine, cnf *config.Conf, reloader *Reloader) {
+	if cnf.Admin.Token == "" {
+		return
+	}
+
+	logger.Info("Init admin endpoints...")
+
+	admin := app.Group(adminUri, adminAuth(cnf.Admin.Token))
+	admin.GET("/sessions/", listSessions)
+	admin.GET("/state/:line_id/:user_id/", getState)
+	admin.DELETE("/state/:line_id/:user_id/", resetState)
+	admin.POST("/state/:line_id/:user_id/goto/", gotoState)
+	admin.GET("/transcript/:line_id/:user_id/", getTranscript)
+	admin.POST("/reload/", reloadConfigs(reloader))
+}
+
+// adminAuth - проверка токена в заголовке Authorization: Bearer <token>
+func adminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			logger.Warning("Rejected admin request from", c.ClientIP())
+
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// получить id линии и пользователя из адреса
+func stateParams(c *gin.Context) (userID, lineID uuid.UUID, ok bool) {
+	lineID, errLine := uuid.Parse(c.Param("line_id"))
+	userID, errUser := uuid.Parse(c.Param("user_id"))
+	if errLine != nil || errUser != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "line_id и user_id должны быть uuid"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, lineID, true
+}
+
+// выполнить изменение состояния в очереди сообщений пользователя, чтобы не пересечься с их обработкой.
+// release, если задан, вызывается после выполнения задачи или если её не удалось поставить в очередь
+func submitAndWait(c *gin.Context, userID, lineID uuid.UUID, task func(ctx context.Context) error, release func()) bool {
+	pool := c.MustGet("pool").(*worker.Pool)
+	if release == nil {
+		release = func() {}
+	}
+
+	done := make(chan error, 1)
+	err := pool.Submit(cache.StateKey(userID, lineID), func(ctx context.Context) {
+		defer release()
+		done <- task(ctx)
+	})
+	if err != nil {
+		release()
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return false
+	}
+
+	select {
+	case err = <-done:
+	case <-c.Request.Context().Done():
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// список всех активных диалогов, можно отфильтровать по ?line_id=
+func listSessions(c *gin.Context) {
+	cacheDB := c.MustGet("cache").(database.StateStore)
+
+	var filterLine uuid.UUID
+	if v := c.Query("line_id"); v != "" {
+		var err error
+		if filterLine, err = uuid.Parse(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "line_id должен быть uuid"})
+			return
+		}
+	}
+
+	keys, err := cacheDB.Keys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions := make([]session, 0, len(keys))
+	for _, key := range keys {
+		userID, lineID, err := cache.ParseStateKey(key)
+		if err != nil || (filterLine != uuid.Nil && filterLine != lineID) {
+			continue
+		}
+
+		chatState, err := cache.LoadState(cacheDB, userID, lineID)
+		if err != nil {
+			continue
+		}
+
+		sessions = append(sessions, session{
+			UserID:        userID,
+			LineID:        lineID,
+			CurrentState:  chatState.CurrentState,
+			PreviousState: chatState.PreviousState,
+		})
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// полное состояние пользователя: история, переменные, заполняемая заявка
+func getState(c *gin.Context) {
+	cacheDB := c.MustGet("cache").(database.StateStore)
+
+	userID, lineID, ok := stateParams(c)
+	if !ok {
+		return
+	}
+
+	chatState, err := cache.LoadState(cacheDB, userID, lineID)
+	if err != nil {
+		if errors.Is(err, database.ErrEntryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "состояние не найдено"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, chatState)
+}
+
+// сбросить состояние, при следующем сообщении диалог начнется заново
+func resetState(c *gin.Context) {
+	cacheDB := c.MustGet("cache").(database.StateStore)
+
+	userID, lineID, ok := stateParams(c)
+	if !ok {
+		return
+	}
+
+	ok = submitAndWait(c, userID, lineID, func(context.Context) error {
+		return cache.DeleteState(cacheDB, userID, lineID)
+	}, nil)
+	if !ok {
+		return
+	}
+
+	logger.Info("Admin reset state for", cache.StateKey(userID, lineID))
+	c.Status(http.StatusNoContent)
+}
+
+// перевести пользователя в указанное меню
+func gotoState(c *gin.Context) {
+	cacheDB := c.MustGet("cache").(database.StateStore)
+	soapcl := c.MustGet("soapcl").(*soap.Client)
+	soapclmtom := c.MustGet("soapclmtom").(*soap.Client)
+	cnf := c.MustGet("cnf").(*config.Conf)
+
+	userID, lineID, ok := stateParams(c)
+	if !ok {
+		return
+	}
+
+	var req gotoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// как и сообщение, перевод держит бота линии, пока не закончится
+	bot, exist := botsConnect.acquire(lineID)
+	if !exist {
+		c.JSON(http.StatusNotFound, gin.H{"error": "линия не обслуживается ботом"})
+		return
+	}
+	menu := bot.menu.Load()
+	if _, exist := menu.Menu[req.Menu]; !exist {
+		bot.tasks.Done()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "меню не найдено: " + req.Menu})
+		return
+	}
+
+	var chatState cache.Chat
+	ok = submitAndWait(c, userID, lineID, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, PROCESS_TIMEOUT)
+		defer cancel()
+
+		chatState = cache.GetState(bot.connect, ctx, cacheDB, userID, lineID)
+
+		// бросаем незавершенные действия (заявку, ввод переменной)
+		err := chatState.ClearCacheOmitemptyFields(cacheDB, userID, lineID)
+		if err != nil {
+			return err
+		}
+
+		newState := req.Menu
+		if req.Send {
+			md := MultiData{
+				cacheDB:    cacheDB,
+				soapcl:     soapcl,
+				soapclmtom: soapclmtom,
+				tickets:    us.SoapTicketCreator{Client: soapcl},
+				cnf:        cnf,
+				menu:       menu,
+				bot:        bot,
+				msg:        messages.Message{LineID: lineID, UserID: userID},
+				chatState:  &chatState,
+			}
+
+			newState, err = SendAnswer(ctx, &md, req.Menu, nil)
+			if err != nil {
+				logger.Warning("Error while send menu", err)
+			}
+		}
+
+		return chatState.ChangeCacheState(cacheDB, userID, lineID, newState, menu.Version)
+	}, bot.tasks.Done)
+	if !ok {
+		return
+	}
+
+	logger.Info("Admin moved", cache.StateKey(userID, lineID), "to", chatState.CurrentState)
+	c.JSON(http.StatusOK, chatState)
+}
+
+// переписка пользователя из архива, период задается ?from=&to= (дата 2006-01-02 или RFC3339)
+func getTranscript(c *gin.Context) {
+	cnf := c.MustGet("cnf").(*config.Conf)
+
+	userID, lineID, ok := stateParams(c)
+	if !ok {
+		return
+	}
+
+	from, errFrom := transcript.ParseTime(c.Query("from"), false)
+	to, errTo := transcript.ParseTime(c.Query("to"), true)
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from и to должны быть датой 2006-01-02 или временем RFC3339"})
+		return
+	}
+
+	if !cnf.Transcript.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "архив переписки выключен"})
+		return
+	}
+
+	entries, err := transcript.Read(cnf.Transcript, transcript.Filter{UserID: userID, LineID: lineID, From: from, To: to})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if entries == nil {
+		entries = []transcript.Entry{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/cache"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/connecttest"
+	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/dedup"
+	"connect-text-bot/internal/transcript"
+	"connect-text-bot/internal/worker"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hooklift/gowsdl/soap"
+)
+
+const testAdminToken = "secret"
+
+type adminServer struct {
+	app     *gin.Engine
+	cacheDB database.StateStore
+	lineID  uuid.UUID
+	fake    *connecttest.Fake
+	menu    *botconfig_parser.Versioned
+}
+
+// сервер с хуком, методами /admin и ботом одной линии с меню routingConfig
+func newAdminServer(t *testing.T) *adminServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	path := filepath.Join(t.TempDir(), "bot.yml")
+	if err := os.WriteFile(path, []byte(routingConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &adminServer{
+		cacheDB: database.ConnectInMemoryCache(database.DEFAULT_STATE_LIFETIME),
+		lineID:  uuid.New(),
+		menu:    botconfig_parser.InitMenuSet([]string{path}).Get(path),
+	}
+	s.fake = connecttest.New(s.lineID)
+	botsConnect.set(s.lineID, Bot{connect: s.fake, menu: s.menu, tasks: &sync.WaitGroup{}})
+
+	pool := worker.New(worker.Config{Count: 2, QueueSize: 10})
+	filter := dedup.New(dedup.Config{})
+	t.Cleanup(func() {
+		filter.Close()
+		botsConnect.remove(s.lineID)
+		_ = pool.Shutdown(context.Background())
+		_ = s.cacheDB.Close()
+	})
+
+	cnf := &config.Conf{Admin: config.Admin{Token: testAdminToken}}
+	soapcl := soap.NewClient("http://127.0.0.1:0/")
+	s.app = gin.New()
+	s.app.Use(
+		config.Inject("cnf", cnf),
+		database.InjectStateStore("cache", s.cacheDB),
+		worker.Inject("pool", pool),
+		dedup.Inject("dedup", filter),
+		transcript.Inject("transcript", nil),
+		func(c *gin.Context) {
+			c.Set("soapcl", soapcl)
+			c.Set("soapclmtom", soapcl)
+		},
+	)
+	s.app.POST(eventUri, Receive)
+	InitAdmin(s.app, cnf, nil)
+	return s
+}
+
+func (s *adminServer) do(method, uri, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, uri, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.app.ServeHTTP(w, req)
+	return w
+}
+
+func (s *adminServer) stateUri(userID uuid.UUID) string {
+	return adminUri + "/state/" + s.lineID.String() + "/" + userID.String() + "/"
+}
+
+// сохранить состояние пользователя в меню state
+func (s *adminServer) setState(t *testing.T, userID uuid.UUID, state string) {
+	t.Helper()
+	chatState := cache.Chat{CurrentState: state, PreviousState: database.GREETINGS}
+	if err := chatState.ChangeCache(s.cacheDB, userID, s.lineID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	s := newAdminServer(t)
+
+	for name, header := range map[string]string{
+		"no header":     "",
+		"no Bearer":     testAdminToken,
+		"wrong token":   "Bearer wrong",
+		"other scheme":  "Basic " + testAdminToken,
+		"empty bearer":  "Bearer ",
+		"lowercase key": "bearer " + testAdminToken,
+	} {
+		req := httptest.NewRequest(http.MethodGet, adminUri+"/sessions/", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		s.app.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", name, w.Code)
+		}
+	}
+
+	if w := s.do(http.MethodGet, adminUri+"/sessions/", ""); w.Code != http.StatusOK {
+		t.Errorf("valid token: status = %d", w.Code)
+	}
+
+	// без токена методы не регистрируются
+	app := gin.New()
+	InitAdmin(app, &config.Conf{}, nil)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, adminUri+"/sessions/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("admin without token: status = %d, want 404", w.Code)
+	}
+}
+
+func TestAdminSessionsAndReset(t *testing.T) {
+	s := newAdminServer(t)
+	user, other := uuid.New(), uuid.New()
+	s.setState(t, user, "sub")
+	s.setState(t, other, database.START)
+	otherLine := cache.Chat{CurrentState: database.START}
+	if err := otherLine.ChangeCache(s.cacheDB, user, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(http.MethodGet, adminUri+"/sessions/?line_id="+s.lineID.String(), "")
+	var sessions []session
+	if err := json.Unmarshal(w.Body.Bytes(), &sessions); err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+	states := map[uuid.UUID]string{}
+	for _, ss := range sessions {
+		states[ss.UserID] = ss.CurrentState
+	}
+	if len(sessions) != 2 || states[user] != "sub" || states[other] != database.START {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+
+	if w := s.do(http.MethodGet, adminUri+"/sessions/?line_id=1", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid line_id: status = %d", w.Code)
+	}
+
+	if w := s.do(http.MethodDelete, s.stateUri(user), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("reset: status = %d %s", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodGet, s.stateUri(user), ""); w.Code != http.StatusNotFound {
+		t.Errorf("state after reset: status = %d", w.Code)
+	}
+	if w := s.do(http.MethodGet, s.stateUri(other), ""); w.Code != http.StatusOK {
+		t.Errorf("state of other user: status = %d", w.Code)
+	}
+}
+
+func TestAdminGoto(t *testing.T) {
+	s := newAdminServer(t)
+	user := uuid.New()
+	s.setState(t, user, database.START)
+
+	loadState := func() cache.Chat {
+		t.Helper()
+		chatState, err := cache.LoadState(s.cacheDB, user, s.lineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chatState
+	}
+
+	// без отправки меню только меняется состояние
+	if w := s.do(http.MethodPost, s.stateUri(user)+"goto/", `{"menu": "sub"}`); w.Code != http.StatusOK {
+		t.Fatalf("goto: status = %d %s", w.Code, w.Body)
+	}
+	chatState := loadState()
+	if chatState.CurrentState != "sub" || chatState.MenuVersion != s.menu.Load().Version {
+		t.Errorf("state = %s on menu version %d, want sub on %d", chatState.CurrentState, chatState.MenuVersion, s.menu.Load().Version)
+	}
+	if calls := s.fake.CallsTo("Send"); len(calls) != 0 {
+		t.Errorf("menu sent without send: %v", calls)
+	}
+
+	// с отправкой пользователь получает меню
+	if w := s.do(http.MethodPost, s.stateUri(user)+"goto/", `{"menu": "start", "send": true}`); w.Code != http.StatusOK {
+		t.Fatalf("goto with send: status = %d %s", w.Code, w.Body)
+	}
+	if chatState := loadState(); chatState.CurrentState != database.START {
+		t.Errorf("state = %s, want start", chatState.CurrentState)
+	}
+	calls := s.fake.CallsTo("Send")
+	if len(calls) == 0 || calls[len(calls)-1].Text != "Главное меню" {
+		t.Errorf("Send calls = %v", calls)
+	}
+
+	if w := s.do(http.MethodPost, s.stateUri(user)+"goto/", `{"menu": "missing"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown menu: status = %d", w.Code)
+	}
+	if w := s.do(http.MethodPost, adminUri+"/state/"+uuid.NewString()+"/"+user.String()+"/goto/", `{"menu": "sub"}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown line: status = %d", w.Code)
+	}
+
+	// все переводы отпустили бота линии, его можно снять
+	removed := make(chan struct{})
+	go func() {
+		b, _ := botsConnect.remove(s.lineID)
+		b.tasks.Wait()
+		close(removed)
+	}()
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		t.Fatal("goto did not release the line bot")
+	}
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// разобрать список адресов и подсетей
+func parseAllowedIPs(list []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(list))
+	for _, v := range list {
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// hookAuth - проверка что сообщение пришло от 1С-Коннект
+func hookAuth(server config.Server) gin.HandlerFunc {
+	allowed, err := parseAllowedIPs(server.AllowedIPs)
+	if err != nil {
+		logger.Crit("Error while parse allowed_ips:", err)
+	}
+
+	reject := func(c *gin.Context, reason string) {
+		logger.Warning("Rejected hook request from", c.ClientIP(), "reason:", reason)
+		metrics.RejectedHooks.WithLabelValues(reason).Inc()
+
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+
+	return func(c *gin.Context) {
+		if len(allowed) != 0 {
+			ip := net.ParseIP(c.ClientIP())
+
+			isAllowed := false
+			for _, n := range allowed {
+				if ip != nil && n.Contains(ip) {
+					isAllowed = true
+					break
+				}
+			}
+			if !isAllowed {
+				reject(c, "ip")
+				return
+			}
+		}
+
+		if server.HookToken != "" {
+			token := c.Query("token")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(server.HookToken)) != 1 {
+				reject(c, "token")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
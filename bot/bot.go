@@ -12,30 +12,44 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"connect-text-bot/internal/botconfig_parser"
 	"connect-text-bot/internal/cache"
 	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/client"
 	"connect-text-bot/internal/connect/messages"
 	"connect-text-bot/internal/connect/requests"
 	"connect-text-bot/internal/connect/response"
 	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/dedup"
 	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/metrics"
+	"connect-text-bot/internal/transcript"
 	"connect-text-bot/internal/us"
+	"connect-text-bot/internal/worker"
 
-	"github.com/allegro/bigcache/v3"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hooklift/gowsdl/soap"
 	"github.com/kballard/go-shellquote"
 )
 
+const (
+	// сколько может длиться обработка одного сообщения
+	PROCESS_TIMEOUT = 2 * time.Minute
+	// сколько может длиться отправка отметки о выбранной подсказке
+	QNA_SELECTED_TIMEOUT = 30 * time.Second
+)
+
 type MultiData struct {
-	cacheDB    *bigcache.BigCache
+	cacheDB    database.StateStore
 	soapcl     *soap.Client
 	soapclmtom *soap.Client
+	tickets    us.TicketCreator
 	cnf        *config.Conf
 	menu       *botconfig_parser.Levels
 	bot        Bot
@@ -44,11 +58,13 @@ type MultiData struct {
 }
 
 func Receive(c *gin.Context) {
-	cacheDB := c.MustGet("cache").(*bigcache.BigCache)
+	cacheDB := c.MustGet("cache").(database.StateStore)
 	soapcl := c.MustGet("soapcl").(*soap.Client)
 	soapclmtom := c.MustGet("soapclmtom").(*soap.Client)
 	cnf := c.MustGet("cnf").(*config.Conf)
-	menus := c.MustGet("menus").(*botconfig_parser.Levels)
+	pool := c.MustGet("pool").(*worker.Pool)
+	filter := c.MustGet("dedup").(*dedup.Filter)
+	archive := c.MustGet("transcript").(*transcript.Archive)
 
 	var msg messages.Message
 	if err := c.BindJSON(&msg); err != nil {
@@ -58,7 +74,20 @@ func Receive(c *gin.Context) {
 		return
 	}
 
-	logger.Debug("Receive message:", msg)
+	// поля для поиска всех записей лога по одному сообщению
+	logFields := []any{"line_id", msg.LineID, "user_id", msg.UserID, "message_id", msg.MessageID}
+	lctx := logger.With(c, logFields...)
+
+	logger.DebugCtx(lctx, "Receive message:", msg)
+	metrics.MessagesReceived.WithLabelValues(strconv.Itoa(int(msg.MessageType))).Inc()
+
+	// 1С-Коннект может повторно доставить сообщение
+	if filter.Seen(msg.MessageID) {
+		logger.InfoCtx(lctx, "Skip duplicate message. message_id=", msg.MessageID.String())
+
+		c.Status(http.StatusOK)
+		return
+	}
 
 	// Реагируем только на сообщения пользователя
 	if (msg.MessageType == messages.MESSAGE_TEXT || msg.MessageType == messages.MESSAGE_FILE) && msg.MessageAuthor != nil && msg.UserID != *msg.MessageAuthor {
@@ -66,42 +95,80 @@ func Receive(c *gin.Context) {
 		return
 	}
 
-	bot, ok := botsConnect[msg.LineID]
+	bot, ok := botsConnect.acquire(msg.LineID)
 	if !ok {
-		logger.Warning("request not find. line_id=", msg.LineID.String())
+		// линию удалили из config.yml, а 1С-Коннект еще не узнал что хук снят
+		logger.WarningCtx(lctx, "Message dropped, line is not served by bot. line_id=", msg.LineID.String())
+		filter.Forget(msg.MessageID)
+
+		c.Status(http.StatusNotFound)
 		return
 	}
 
-	go func() {
-		chatState := cache.GetState(bot.connect, c, cacheDB, msg.UserID, msg.LineID)
+	// сообщения одного пользователя на линии обрабатываем строго по очереди
+	// обработка продолжается после ответа на запрос, поэтому контекст запроса не используем
+	err := pool.Submit(cache.StateKey(msg.UserID, msg.LineID), func(ctx context.Context) {
+		defer bot.tasks.Done()
+
+		ctx, cancel := context.WithTimeout(logger.With(ctx, logFields...), PROCESS_TIMEOUT)
+		defer cancel()
+
+		archive.Inbound(msg)
+
+		chatState := cache.GetState(bot.connect, ctx, cacheDB, msg.UserID, msg.LineID)
+		ctx = logger.With(ctx, "state", chatState.CurrentState)
 
 		md := MultiData{
 			cacheDB:    cacheDB,
 			soapcl:     soapcl,
 			soapclmtom: soapclmtom,
+			tickets:    us.SoapTicketCreator{Client: soapcl},
 			cnf:        cnf,
-			menu:       menus,
+			menu:       bot.menu.Load(),
 			bot:        bot,
 			msg:        msg,
 			chatState:  &chatState,
 		}
 
-		newState, err := processMessage(&md)
+		newState, err := processMessage(ctx, &md)
 		if err != nil {
-			logger.Warning("Error processMessage", err)
+			logger.WarningCtx(ctx, "Error processMessage", err)
 		}
 
-		err = md.chatState.ChangeCacheState(cacheDB, msg.UserID, msg.LineID, newState)
+		// запоминаем версию меню, на которой пользователь перешел в новое состояние
+		err = md.chatState.ChangeCacheState(cacheDB, msg.UserID, msg.LineID, newState, md.menu.Version)
 		if err != nil {
-			logger.Warning("Error changeState", err)
+			logger.WarningCtx(ctx, "Error changeState", err)
 		}
 
-		logger.Debug("Cache:", chatState)
-	}()
+		logger.DebugCtx(logger.With(ctx, "state", newState), "Cache:", chatState)
+	})
+	if err != nil {
+		logger.WarningCtx(lctx, "Error while queue message", err)
+		bot.tasks.Done()
+		filter.Forget(msg.MessageID)
+
+		// Connect повторит доставку позже
+		c.Status(http.StatusServiceUnavailable)
+		return
+	}
 
 	c.Status(http.StatusOK)
 }
 
+// templateData - данные пользователя для шаблонов в текстах и условий show_if
+func templateData(state *cache.Chat) any {
+	return struct {
+		User   response.User
+		Var    map[string]string
+		Ticket database.Ticket
+	}{
+		User:   state.User,
+		Var:    state.Vars,
+		Ticket: state.Ticket,
+	}
+}
+
 // заполнить шаблон данными
 func fillTemplateWithInfo(state *cache.Chat, text string) (result string, err error) {
 	// проверяем есть ли шаблон в тексте чтобы лишний раз не выполнять обработку
@@ -115,20 +182,9 @@ func fillTemplateWithInfo(state *cache.Chat, text string) (result string, err er
 		return
 	}
 
-	// создаем объединенные данные
-	combinedData := struct {
-		User   response.User
-		Var    map[string]string
-		Ticket database.Ticket
-	}{
-		User:   state.User,
-		Var:    state.Vars,
-		Ticket: state.Ticket,
-	}
-
 	// заполняем шаблон
 	var templOutput bytes.Buffer
-	err = templ.Execute(&templOutput, combinedData)
+	err = templ.Execute(&templOutput, templateData(state))
 	if err != nil {
 		return
 	}
@@ -186,10 +242,10 @@ func SendAnswerMenuChat(ctx context.Context, md *MultiData, answer *botconfig_pa
 // отправить файл из меню
 func SendAnswerMenuFile(ctx context.Context, md *MultiData, answer *botconfig_parser.Answer, keyboard *[][]requests.KeyboardKey) {
 	if answer.File != "" {
-		if isImage, filePath, err := getFileInfo(answer.File, md.cnf.FilesDir); err == nil {
+		if isImage, filePath, err := getFileInfo(answer.File, md.bot.filesDir); err == nil {
 			err = md.bot.connect.SendFile(ctx, md.msg.UserID, isImage, answer.File, filePath, &answer.FileText, keyboard)
 			if err != nil {
-				logger.Warning(err)
+				logger.WarningCtx(ctx, err)
 			}
 		} else {
 			_ = md.bot.connect.Send(ctx, md.msg.UserID, md.menu.ErrorMessages.FailedSendFile, keyboard)
@@ -201,6 +257,12 @@ func SendAnswerMenuFile(ctx context.Context, md *MultiData, answer *botconfig_pa
 func SendAnswerMenu(ctx context.Context, md *MultiData, answer []*botconfig_parser.Answer, keyboard *[][]requests.KeyboardKey) error {
 	var toSend *[][]requests.KeyboardKey
 
+	// сообщения, условие show_if которых не выполнено, не отправляем
+	data := templateData(md.chatState)
+	answer = slices.DeleteFunc(slices.Clone(answer), func(a *botconfig_parser.Answer) bool {
+		return !a.Visible(data)
+	})
+
 	for i := range len(answer) {
 		// Отправляем клаву только с последним сообщением.
 		// Т.к в дп4 криво отображается.
@@ -212,14 +274,17 @@ func SendAnswerMenu(ctx context.Context, md *MultiData, answer []*botconfig_pars
 			return err
 		}
 		SendAnswerMenuFile(ctx, md, answer[i], toSend)
-		time.Sleep(250 * time.Millisecond)
 	}
 	return nil
 }
 
 // отобразить меню и выполнить do_button если есть
 func SendAnswer(ctx context.Context, md *MultiData, goTo string, err error) (string, error) {
-	errMenu := SendAnswerMenu(ctx, md, md.menu.Menu[goTo].Answer, md.menu.GenKeyboard(goTo))
+	if _, ok := md.menu.Menu[goTo]; !ok {
+		return stateVanished(ctx, md, goTo)
+	}
+
+	errMenu := SendAnswerMenu(ctx, md, md.menu.Menu[goTo].Answer, md.menu.GenKeyboard(goTo, templateData(md.chatState)))
 	if errMenu != nil {
 		return finalSend(ctx, md, "", err)
 	}
@@ -237,6 +302,21 @@ func SendAnswer(ctx context.Context, md *MultiData, goTo string, err error) (str
 	return goTo, err
 }
 
+// stateVanished - меню state нет в текущей версии конфига, пользователь возвращается в начало.
+// Если диалог начат на предыдущей версии меню, значит меню удалили при обновлении конфига
+func stateVanished(ctx context.Context, md *MultiData, state string) (string, error) {
+	text := md.menu.ErrorMessages.CommandUnknown
+	if md.chatState.MenuVersion != 0 && md.chatState.MenuVersion != md.menu.Version {
+		logger.InfoCtx(ctx, "Menu", state, "removed in menu version", md.menu.Version, "session started on version", md.chatState.MenuVersion)
+		text = md.menu.ErrorMessages.MenuUpdated
+	} else {
+		logger.WarningCtx(ctx, "неизвестное состояние: ", state)
+	}
+
+	err := md.bot.connect.Send(ctx, md.msg.UserID, text, md.menu.GenKeyboard(database.START, templateData(md.chatState)))
+	return database.GREETINGS, err
+}
+
 // переход на следующую стадию формирования заявки
 func nextStageTicketButton(ctx context.Context, md *MultiData, button *botconfig_parser.TicketButton, nextVar string) (string, error) {
 	ticket := database.Ticket{}
@@ -491,15 +571,12 @@ func getGoToIfClickedBackBtn(btn *botconfig_parser.Button, md *MultiData, ignore
 }
 
 // обработать событие произошедшее в чате
-func processMessage(md *MultiData) (string, error) {
-	time.Sleep(250 * time.Millisecond)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
+func processMessage(ctx context.Context, md *MultiData) (string, error) {
 	var err error
 	chatState, msg, bot, menu := md.chatState, md.msg, md.bot, md.menu
 
+	logger.DebugCtx(ctx, "Process message type", msg.MessageType, "in state", chatState.CurrentState)
+
 	switch msg.MessageType {
 	// Первый запуск.
 	case messages.MESSAGE_TREATMENT_START_BY_USER:
@@ -546,19 +623,19 @@ func processMessage(md *MultiData) (string, error) {
 
 			if menu.FirstGreeting {
 				_ = bot.connect.Send(ctx, msg.UserID, menu.GreetingMessage, nil)
-				time.Sleep(time.Second)
 			}
 			return SendAnswer(ctx, md, database.START, err)
 
 		// пользователь попадет сюда в случае регистрации заявки
 		case database.CREATE_TICKET:
-			btn := GetClickedButton(menu, chatState.CurrentState, text)
+			btn := GetClickedButton(menu, chatState, chatState.CurrentState, text)
 			tBtn := chatState.GetCacheSavedButton().TicketButton
 			ticket := database.Ticket{}
 
 			// переходим если нажата Отмена
 			goTo := getGoToIfClickedBackBtn(btn, md, true)
 			if goTo != "" {
+				countClick(chatState.CurrentState, btn)
 				// перейти в определенное меню если настроен параметр goto
 				if tBtn.Goto != "" {
 					goTo = tBtn.Goto
@@ -581,6 +658,7 @@ func processMessage(md *MultiData) (string, error) {
 
 			// проверяем нажата ли кнопка Назад
 			if btn != nil && btn.Goto == database.CREATE_TICKET_PREV_STAGE {
+				countClick(chatState.CurrentState, btn)
 				return prevStageTicketButton(ctx, md, tBtn, varName)
 			}
 
@@ -683,14 +761,19 @@ func processMessage(md *MultiData) (string, error) {
 					_ = bot.connect.Send(ctx, msg.UserID, "Заявка регистрируется, ожидайте...", nil)
 
 					// регистрируем заявку
-					r, err := us.CreateTicket(ctx, md.soapcl, msg.UserID, msg.LineID, chatState.GetCacheTicket())
+					r, err := md.tickets.CreateTicket(ctx, msg.UserID, msg.LineID, chatState.GetCacheTicket())
 					if err != nil {
 						return finalSend(ctx, md, "", err)
 					}
 
 					// даем время чтобы загрузилась заявка
+				wait:
 					for range 10 {
-						time.Sleep(4 * time.Second)
+						select {
+						case <-ctx.Done():
+							break wait
+						case <-time.After(4 * time.Second):
+						}
 
 						_, err := bot.connect.GetTicket(ctx, uuid.MustParse(r["ServiceRequestID"]))
 						if err == nil {
@@ -725,9 +808,10 @@ func processMessage(md *MultiData) (string, error) {
 			}
 
 			// переходим если нажата BackButton
-			btn := GetClickedButton(menu, chatState.CurrentState, text)
+			btn := GetClickedButton(menu, chatState, chatState.CurrentState, text)
 			goTo := getGoToIfClickedBackBtn(btn, md, true)
 			if goTo != "" {
+				countClick(chatState.CurrentState, btn)
 				return SendAnswer(ctx, md, goTo, err)
 			}
 
@@ -742,15 +826,14 @@ func processMessage(md *MultiData) (string, error) {
 			// В редисе может остаться состояние которого, нет в конфиге.
 			cm, ok := menu.Menu[currentMenu]
 			if !ok {
-				logger.Warning("неизвестное состояние: ", currentMenu)
-				err = bot.connect.Send(ctx, msg.UserID, menu.ErrorMessages.CommandUnknown, menu.GenKeyboard(database.START))
-				return database.GREETINGS, err
+				return stateVanished(ctx, md, currentMenu)
 			}
 
 			// определяем какая кнопка была нажата
-			btn := GetClickedButton(menu, currentMenu, text)
+			btn := GetClickedButton(menu, chatState, currentMenu, text)
 
 			if btn != nil {
+				countClick(currentMenu, btn)
 				gt, err := triggerButton(ctx, md, btn)
 				_ = chatState.HistoryStateAppend(md.cacheDB, msg.UserID, msg.LineID, gt)
 				return gt, err
@@ -758,7 +841,7 @@ func processMessage(md *MultiData) (string, error) {
 				if !cm.QnaDisable && menu.UseQNA.Enabled {
 					return qnaResponse(ctx, md, currentMenu)
 				}
-				err = bot.connect.Send(ctx, msg.UserID, menu.ErrorMessages.CommandUnknown, menu.GenKeyboard(currentMenu))
+				err = bot.connect.Send(ctx, msg.UserID, menu.ErrorMessages.CommandUnknown, menu.GenKeyboard(currentMenu, templateData(chatState)))
 				return chatState.CurrentState, err
 			}
 		}
@@ -778,7 +861,14 @@ func qnaResponse(ctx context.Context, md *MultiData, currentMenu string) (string
 	qnaText, isClose, requestID, resultID := getMessageFromQNA(ctx, md)
 	if qnaText != "" {
 		// Была подсказка
-		go md.bot.connect.QnaSelected(ctx, requestID, resultID)
+		metrics.QnaResponses.WithLabelValues("hit").Inc()
+		// отметку о выбранной подсказке отправляем не дожидаясь, контекст обработки сообщения
+		// к этому времени может быть уже отменен, поэтому у запроса свой контекст
+		go func(connect client.ConnectAPI) {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), QNA_SELECTED_TIMEOUT)
+			defer cancel()
+			connect.QnaSelected(ctx, requestID, resultID)
+		}(md.bot.connect)
 
 		if isClose {
 			err = md.bot.connect.Send(ctx, md.msg.UserID, qnaText, nil)
@@ -786,10 +876,11 @@ func qnaResponse(ctx context.Context, md *MultiData, currentMenu string) (string
 			return currentMenu, err
 		}
 
-		err = md.bot.connect.Send(ctx, md.msg.UserID, qnaText, md.menu.GenKeyboard(currentMenu))
+		err = md.bot.connect.Send(ctx, md.msg.UserID, qnaText, md.menu.GenKeyboard(currentMenu, templateData(md.chatState)))
 		return currentMenu, err
 	}
 
+	metrics.QnaResponses.WithLabelValues("miss").Inc()
 	return SendAnswer(ctx, md, database.FAIL_QNA, err)
 }
 
@@ -799,8 +890,10 @@ func triggerButton(ctx context.Context, md *MultiData, btn *botconfig_parser.But
 		return finalSend(ctx, md, "", fmt.Errorf("Кнопка не передана в triggerButton"))
 	}
 
+	logger.DebugCtx(ctx, "Trigger button", btn.ButtonID, btn.ButtonText)
+
 	var err error
-	chatState, msg, bot, menu, cnf := md.chatState, md.msg, md.bot, md.menu, md.cnf
+	chatState, msg, bot, menu := md.chatState, md.msg, md.bot, md.menu
 
 	goTo := btn.Goto
 	if gt := getGoToIfClickedBackBtn(btn, md, false); gt != "" {
@@ -829,7 +922,7 @@ func triggerButton(ctx context.Context, md *MultiData, btn *botconfig_parser.But
 		}
 
 		// назначаем если свободен
-		err = bot.connect.AppointSpec(ctx, msg.UserID, cnf.SpecID, *btn.AppointSpecButton)
+		err = bot.connect.AppointSpec(ctx, msg.UserID, bot.specID, *btn.AppointSpecButton)
 		return database.GREETINGS, err
 	}
 	if btn.AppointRandomSpecFromListButton != nil && len(*btn.AppointRandomSpecFromListButton) != 0 {
@@ -864,7 +957,7 @@ func triggerButton(ctx context.Context, md *MultiData, btn *botconfig_parser.But
 		rns := rand.NewSource(seed)
 		rng := rand.New(rns)
 		randomIndex := rng.Intn(lenNeededSpec)
-		err = bot.connect.AppointSpec(ctx, msg.UserID, cnf.SpecID, neededSpec[randomIndex])
+		err = bot.connect.AppointSpec(ctx, msg.UserID, bot.specID, neededSpec[randomIndex])
 		return database.GREETINGS, err
 	}
 	if btn.RerouteButton != nil && *btn.RerouteButton != uuid.Nil {
@@ -905,9 +998,10 @@ func triggerButton(ctx context.Context, md *MultiData, btn *botconfig_parser.But
 			}
 		}
 
-		// выполняем команду на устройстве
-		cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
+		// выполняем команду на устройстве, по истечении времени обработки сообщения она завершается
+		cmd := exec.CommandContext(ctx, cmdParts[0], cmdParts[1:]...)
 		cmdOutput, err := cmd.CombinedOutput()
+		metrics.ExecButton.WithLabelValues(strconv.Itoa(cmd.ProcessState.ExitCode())).Inc()
 		if err != nil {
 			return finalSend(ctx, md, "Ошибка: "+err.Error(), err)
 		}
@@ -930,7 +1024,10 @@ func triggerButton(ctx context.Context, md *MultiData, btn *botconfig_parser.But
 			}
 			*keyboard = append(*keyboard, []requests.KeyboardKey{{Text: r}})
 		}
-		*keyboard = append(*keyboard, *menu.GenKeyboard(database.WAIT_SEND)...)
+		// кнопки WAIT_SEND могут быть скрыты условием show_if
+		if waitKeyboard := menu.GenKeyboard(database.WAIT_SEND, templateData(chatState)); waitKeyboard != nil {
+			*keyboard = append(*keyboard, *waitKeyboard...)
+		}
 
 		// Сообщаем пользователю что требуем и запускаем ожидание данных
 		if btn.SaveToVar.SendText != nil && *btn.SaveToVar.SendText != "" {
@@ -982,16 +1079,22 @@ func triggerButton(ctx context.Context, md *MultiData, btn *botconfig_parser.But
 }
 
 // определить какая кнопка была нажата
-func GetClickedButton(menu *botconfig_parser.Levels, currentMenu, text string) (btn *botconfig_parser.Button) {
-	btn = menu.GetButton(currentMenu, text)
+func GetClickedButton(menu *botconfig_parser.Levels, state *cache.Chat, currentMenu, text string) (btn *botconfig_parser.Button) {
+	data := templateData(state)
+	btn = menu.GetButton(currentMenu, text, data)
 	if btn == nil {
 		text = strings.ReplaceAll(text, "«", "\"")
 		text = strings.ReplaceAll(text, "»", "\"")
-		btn = menu.GetButton(currentMenu, text)
+		btn = menu.GetButton(currentMenu, text, data)
 	}
 	return
 }
 
+// countClick - учесть нажатие кнопки, по id а не тексту: текст может содержать шаблоны
+func countClick(menu string, btn *botconfig_parser.Button) {
+	metrics.ButtonClicks.WithLabelValues(menu, btn.ButtonID).Inc()
+}
+
 // выполнить Send и вывести Final меню
 func finalSend(ctx context.Context, md *MultiData, finalMsg string, err error) (string, error) {
 	if finalMsg == "" {
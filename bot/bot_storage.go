@@ -1,17 +1,108 @@
 package bot
 
 import (
+	"sync"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
 	"connect-text-bot/internal/connect/client"
 
 	"github.com/google/uuid"
 )
 
 type (
-	fConnect map[uuid.UUID]Bot
+	// fConnect - боты по линиям, линии добавляются и удаляются при перечитывании config.yml
+	fConnect struct {
+		mu   sync.RWMutex
+		bots map[uuid.UUID]Bot
+	}
 
+	// Bot - бот одной линии
 	Bot struct {
-		connect *client.Client
+		connect client.ConnectAPI
+		// меню линии, может быть общим для нескольких линий
+		menu *botconfig_parser.Versioned
+		// директория с файлами меню линии
+		filesDir string
+		// специалист, от лица которого работает бот
+		specID *uuid.UUID
+		// настройки линии, с которыми создан бот
+		line config.LineConfig
+		// принятые в обработку сообщения линии, хук удаленной линии снимается после их обработки
+		tasks *sync.WaitGroup
 	}
 )
 
-var botsConnect = make(fConnect)
+var botsConnect = &fConnect{bots: make(map[uuid.UUID]Bot)}
+
+func (f *fConnect) get(lineID uuid.UUID) (Bot, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	b, ok := f.bots[lineID]
+	return b, ok
+}
+
+// acquire - бот линии для обработки сообщения, после обработки вызвать bot.tasks.Done().
+// Под блокировкой, чтобы remove не пропустил сообщение, принятое одновременно с удалением линии
+func (f *fConnect) acquire(lineID uuid.UUID) (Bot, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	b, ok := f.bots[lineID]
+	if ok {
+		b.tasks.Add(1)
+	}
+	return b, ok
+}
+
+func (f *fConnect) set(lineID uuid.UUID, b Bot) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bots[lineID] = b
+}
+
+func (f *fConnect) remove(lineID uuid.UUID) (Bot, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.bots[lineID]
+	delete(f.bots, lineID)
+	return b, ok
+}
+
+// lines - линии, которые сейчас обслуживает бот
+func (f *fConnect) lines() []uuid.UUID {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	ids := make([]uuid.UUID, 0, len(f.bots))
+	for id := range f.bots {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// configs - настройки линий, которые сейчас обслуживает бот
+func (f *fConnect) configs() []config.LineConfig {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	lines := make([]config.LineConfig, 0, len(f.bots))
+	for _, b := range f.bots {
+		lines = append(lines, b.line)
+	}
+	return lines
+}
+
+// waitTasks - дождаться обработки принятых сообщений, false если не дождались за timeout
+func (b Bot) waitTasks(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		b.tasks.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,302 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/cache"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const routingConfig = `menus:
+  start:
+    answer:
+      - chat: "Главное меню"
+    buttons:
+      - button:
+          id: 1
+          text: "Подменю"
+          goto: sub
+      - button:
+          id: 2
+          text: "Вложенное"
+          menu:
+            id: nested
+            answer:
+              - chat: "Вложенное меню"
+            buttons:
+              - button:
+                  id: 1
+                  text: "Назад"
+                  back_button: true
+      - button:
+          id: 3
+          text: "Указать договор"
+          save_to_var:
+            var_name: contract
+            send_text: "Номер договора?"
+            do_button:
+              chat:
+                - chat: "Договор {{ .Var.contract }} сохранен"
+              goto: sub
+      - button:
+          id: 4
+          text: "Заявка"
+          ticket_button:
+            channel_id: 00000000-0000-0000-0000-0000000000c1
+            ticket_info: "Тема: {{ .Ticket.Theme }}, вид работ: {{ .Ticket.ServiceType.Name }}"
+            goto: start
+            data:
+              theme:
+                text: "Введите тему"
+              description:
+                value: "Описание от {{ .User.Name }}"
+              executor:
+                value: 00000000-0000-0000-0000-000000000001
+              service:
+                text: "Выберите услугу"
+              type:
+                text: "Выберите вид работ"
+      - button:
+          id: 5
+          text: "Закрыть"
+          close_button: true
+  sub:
+    answer:
+      - chat: "Подменю"
+    buttons:
+      - button:
+          id: 1
+          text: "Назад"
+          back_button: true
+      - button:
+          id: 2
+          text: "В конец"
+          goto: final_menu
+`
+
+// сеанс диалога с меню из routingConfig, пользователь уже в меню start
+func newTestSession(t *testing.T) *simSession {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.yml")
+	if err := os.WriteFile(path, []byte(routingConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	menus, err := botconfig_parser.LoadLevels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newSimSession(&config.Conf{FilesDir: dir}, menus, simUser)
+	t.Cleanup(func() { _ = s.Close() })
+
+	sendExpect(t, s, messages.MESSAGE_TREATMENT_START_BY_USER, "", database.GREETINGS)
+	sendExpect(t, s, messages.MESSAGE_TEXT, "Привет", database.START)
+	return s
+}
+
+// отправить сообщение и проверить новое состояние
+func sendExpect(t *testing.T, s *simSession, messageType messages.MessageType, text, state string) []SimEvent {
+	t.Helper()
+	events, newState, err := s.send(messageType, text)
+	if err != nil {
+		t.Fatalf("send %q: %v", text, err)
+	}
+	if newState != state {
+		t.Fatalf("send %q: state = %s, want %s; events: %v", text, newState, state, events)
+	}
+	return events
+}
+
+// тексты сообщений бота
+func eventTexts(events []SimEvent) (texts []string) {
+	for _, e := range events {
+		if e.Action == "text" {
+			texts = append(texts, e.Text)
+		}
+	}
+	return
+}
+
+// клавиатура последнего сообщения с клавиатурой
+func lastKeyboard(events []SimEvent) []string {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Keyboard != nil {
+			return events[i].KeyboardTexts()
+		}
+	}
+	return nil
+}
+
+func TestButtonRouting(t *testing.T) {
+	s := newTestSession(t)
+
+	// кнопку можно нажать по номеру и по тексту
+	events := sendExpect(t, s, messages.MESSAGE_TEXT, "1", "sub")
+	if !slices.Contains(eventTexts(events), "Подменю") {
+		t.Errorf("answer of sub not sent: %v", events)
+	}
+	if got := lastKeyboard(events); !slices.Equal(got, []string{"Назад", "В конец"}) {
+		t.Errorf("keyboard of sub = %v", got)
+	}
+
+	sendExpect(t, s, messages.MESSAGE_TEXT, "Назад", database.START)
+
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "Вложенное", "nested")
+	if !slices.Contains(eventTexts(events), "Вложенное меню") {
+		t.Errorf("answer of nested menu not sent: %v", events)
+	}
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "1", database.START)
+	if !slices.Contains(eventTexts(events), "Главное меню") {
+		t.Errorf("answer of start not sent after back: %v", events)
+	}
+}
+
+func TestFinalMenu(t *testing.T) {
+	s := newTestSession(t)
+
+	sendExpect(t, s, messages.MESSAGE_TEXT, "1", "sub")
+	sendExpect(t, s, messages.MESSAGE_TEXT, "2", database.FINAL)
+
+	// закрытие обращения
+	sendExpect(t, s, messages.MESSAGE_TREATMENT_START_BY_USER, "", database.GREETINGS)
+	sendExpect(t, s, messages.MESSAGE_TEXT, "Привет", database.START)
+	events := sendExpect(t, s, messages.MESSAGE_TEXT, "Закрыть", database.GREETINGS)
+	if !slices.ContainsFunc(events, func(e SimEvent) bool { return e.Action == "close" }) {
+		t.Errorf("treatment not closed: %v", events)
+	}
+	if calls := s.fake.CallsTo("CloseTreatment"); len(calls) != 1 {
+		t.Errorf("CloseTreatment calls = %v", calls)
+	}
+}
+
+func TestSaveToVar(t *testing.T) {
+	s := newTestSession(t)
+
+	events := sendExpect(t, s, messages.MESSAGE_TEXT, "3", database.WAIT_SEND)
+	if !slices.Contains(eventTexts(events), "Номер договора?") {
+		t.Errorf("send_text not sent: %v", events)
+	}
+
+	// после ввода выполняется do_button
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "A-42", "sub")
+	if !slices.Contains(eventTexts(events), "Договор A-42 сохранен") {
+		t.Errorf("do_button chat not sent: %v", events)
+	}
+
+	chatState, err := cache.LoadState(s.cacheDB, s.userID, s.lineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chatState.Vars["contract"] != "A-42" {
+		t.Errorf("vars = %v, want contract=A-42", chatState.Vars)
+	}
+}
+
+func TestTicketButton(t *testing.T) {
+	if testing.Short() {
+		t.Skip("регистрация заявки ждет загрузки заявки несколько секунд")
+	}
+	s := newTestSession(t)
+
+	events := sendExpect(t, s, messages.MESSAGE_TEXT, "Заявка", database.CREATE_TICKET)
+	if !slices.Contains(eventTexts(events), "Введите тему") {
+		t.Fatalf("theme stage not started: %v", events)
+	}
+	if got := lastKeyboard(events); !slices.Equal(got, []string{"Далее", "Назад", "Отмена"}) {
+		t.Errorf("keyboard of theme stage = %v", got)
+	}
+
+	// описание и исполнитель заданы в value, шаги пропускаются
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "Не работает печать", database.CREATE_TICKET)
+	if !slices.Contains(eventTexts(events), "Выберите услугу") {
+		t.Fatalf("service stage not started: %v", events)
+	}
+	if got := lastKeyboard(events); !slices.Contains(got, "Консультация") {
+		t.Fatalf("keyboard of service stage = %v", got)
+	}
+
+	// значение не из списка
+	sendExpect(t, s, messages.MESSAGE_TEXT, "Ремонт", database.CREATE_TICKET)
+
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "Консультация", database.CREATE_TICKET)
+	if !slices.Contains(eventTexts(events), "Выберите вид работ") {
+		t.Fatalf("type stage not started: %v", events)
+	}
+
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "Запрос на обслуживание", database.CREATE_TICKET)
+	if !slices.Contains(eventTexts(events), "Тема: Не работает печать, вид работ: Запрос на обслуживание") {
+		t.Fatalf("ticket info not sent: %v", events)
+	}
+	if got := lastKeyboard(events); !slices.Equal(got, []string{"Подтверждаю", "Назад", "Отмена"}) {
+		t.Errorf("keyboard of final stage = %v", got)
+	}
+
+	// шаг назад возвращает к выбору вида работ
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "Назад", database.CREATE_TICKET)
+	if !slices.Contains(eventTexts(events), "Выберите вид работ") {
+		t.Fatalf("back from final stage: %v", events)
+	}
+	sendExpect(t, s, messages.MESSAGE_TEXT, "Запрос на обслуживание", database.CREATE_TICKET)
+
+	events = sendExpect(t, s, messages.MESSAGE_TEXT, "Подтверждаю", database.START)
+	i := slices.IndexFunc(events, func(e SimEvent) bool { return e.Action == "ticket" })
+	if i < 0 {
+		t.Fatalf("ticket not created: %v", events)
+	}
+	if events[i].Text != "Не работает печать" {
+		t.Errorf("ticket theme = %q", events[i].Text)
+	}
+
+	// данные заявки очищены
+	chatState, err := cache.LoadState(s.cacheDB, s.userID, s.lineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chatState.Ticket.Theme != "" {
+		t.Errorf("ticket data not cleared: %+v", chatState.Ticket)
+	}
+}
+
+func TestExecButtonCanceled(t *testing.T) {
+	s := newTestSession(t)
+	chatState, err := cache.LoadState(s.cacheDB, s.userID, s.lineID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := MultiData{
+		cacheDB:   s.cacheDB,
+		cnf:       s.cnf,
+		menu:      s.menus,
+		bot:       Bot{connect: s.fake},
+		chatState: &chatState,
+		msg:       messages.Message{LineID: s.lineID, UserID: s.userID},
+	}
+	killed := testutil.ToFloat64(metrics.ExecButton.WithLabelValues("-1"))
+
+	// команда завершается вместе с обработкой сообщения
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = triggerButton(ctx, &md, &botconfig_parser.Button{ExecButton: "sleep 10"})
+	if err == nil {
+		t.Error("canceled command returned no error")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("command not killed when context expired")
+	}
+	if got := testutil.ToFloat64(metrics.ExecButton.WithLabelValues("-1")) - killed; got != 1 {
+		t.Errorf("exec_button{exit_code=-1} += %v, want 1", got)
+	}
+}
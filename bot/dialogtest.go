@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/connect/response"
+
+	"github.com/goccy/go-yaml"
+)
+
+type (
+	// Dialog - сценарий диалога с ботом и ожидаемые ответы на каждом шаге
+	Dialog struct {
+		Name string `yaml:"name"`
+		// данные пользователя для шаблонов, по умолчанию тестовый пользователь
+		User *struct {
+			Name       string `yaml:"name"`
+			Surname    string `yaml:"surname"`
+			Patronymic string `yaml:"patronymic"`
+			Email      string `yaml:"email"`
+			Phone      string `yaml:"phone"`
+		} `yaml:"user"`
+		Steps []DialogStep `yaml:"steps"`
+
+		// файл из которого загружен сценарий
+		Path string `yaml:"-"`
+	}
+
+	DialogStep struct {
+		// сообщение пользователя, для event: file - имя файла
+		Send string `yaml:"send"`
+		// событие вместо сообщения: start, close, file
+		Event string `yaml:"event"`
+		// ожидаемые действия бота по порядку, если не указано - не проверяются
+		Expect []DialogExpect `yaml:"expect"`
+		// меню в котором должен оказаться пользователь
+		State string `yaml:"state"`
+	}
+
+	// DialogExpect - ожидаемое действие бота, задается одно из text, contains, file, action
+	DialogExpect struct {
+		// точный текст сообщения
+		Text *string `yaml:"text"`
+		// часть текста сообщения
+		Contains string `yaml:"contains"`
+		// имя отправленного файла
+		File string `yaml:"file"`
+		// drop_keyboard, close, redirect, reroute, appoint_spec, ticket
+		Action string `yaml:"action"`
+		// тексты кнопок клавиатуры по порядку, если не указано - не проверяются
+		Keyboard *[]string `yaml:"keyboard"`
+	}
+)
+
+func (x DialogExpect) match(e SimEvent) bool {
+	switch {
+	case x.Text != nil || x.Contains != "":
+		if e.Action != "text" {
+			return false
+		}
+		if x.Text != nil && strings.TrimSpace(*x.Text) != strings.TrimSpace(e.Text) {
+			return false
+		}
+		if !strings.Contains(e.Text, x.Contains) {
+			return false
+		}
+	case x.File != "":
+		if e.Action != "file" || e.File != x.File {
+			return false
+		}
+	default:
+		if e.Action != x.Action {
+			return false
+		}
+	}
+
+	return x.Keyboard == nil || slices.Equal(*x.Keyboard, e.KeyboardTexts())
+}
+
+func (x DialogExpect) String() string {
+	var str string
+	switch {
+	case x.Text != nil:
+		str = "text: " + *x.Text
+	case x.Contains != "":
+		str = "contains: " + x.Contains
+	case x.File != "":
+		str = "file: " + x.File
+	default:
+		str = "action: " + x.Action
+	}
+	if x.Keyboard != nil {
+		str += fmt.Sprintf("\n    keyboard: %q", *x.Keyboard)
+	}
+	return str
+}
+
+// LoadDialogs - загрузить сценарии из файла или всех yml/yaml файлов каталога
+func LoadDialogs(path string) ([]Dialog, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yml" || ext == ".yaml") {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+	}
+
+	dialogs := make([]Dialog, 0, len(files))
+	for _, f := range files {
+		input, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		var d Dialog
+		if err := yaml.Unmarshal(input, &d); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		d.Path = f
+		if d.Name == "" {
+			d.Name = filepath.Base(f)
+		}
+		dialogs = append(dialogs, d)
+	}
+	return dialogs, nil
+}
+
+// RunDialog - воспроизвести сценарий, возвращает описание расхождений с ожидаемым
+func RunDialog(cnf *config.Conf, menus *botconfig_parser.Levels, d Dialog) (diffs []string) {
+	user := simUser
+	if d.User != nil {
+		user = response.User{
+			Name:       d.User.Name,
+			Surname:    d.User.Surname,
+			Patronymic: d.User.Patronymic,
+			Email:      d.User.Email,
+			Phone:      d.User.Phone,
+		}
+	}
+
+	s := newSimSession(cnf, menus, user)
+	defer s.Close()
+
+	for i, step := range d.Steps {
+		var messageType messages.MessageType
+		switch step.Event {
+		case "":
+			messageType = messages.MESSAGE_TEXT
+		case "start":
+			messageType = messages.MESSAGE_TREATMENT_START_BY_USER
+		case "close":
+			messageType = messages.MESSAGE_TREATMENT_CLOSE
+		case "file":
+			messageType = messages.MESSAGE_FILE
+		default:
+			return append(diffs, fmt.Sprintf("шаг %d: неизвестное событие %s", i+1, step.Event))
+		}
+
+		events, state, err := s.send(messageType, step.Send)
+		if err != nil {
+			diffs = append(diffs, fmt.Sprintf("шаг %d (%s): ошибка обработки: %v", i+1, step.Send, err))
+		}
+
+		if step.Expect != nil && !matchEvents(step.Expect, events) {
+			diff := fmt.Sprintf("шаг %d (%s): ответ бота отличается\n  ожидалось:", i+1, step.Send)
+			for _, x := range step.Expect {
+				diff += "\n    " + strings.ReplaceAll(x.String(), "\n", "\n    ")
+			}
+			diff += "\n  получено:"
+			for _, e := range events {
+				diff += "\n    " + strings.ReplaceAll(e.String(), "\n", "\n    ")
+			}
+			diffs = append(diffs, diff)
+		}
+
+		if step.State != "" && step.State != state {
+			diffs = append(diffs, fmt.Sprintf("шаг %d (%s): ожидалось меню %s, получено %s", i+1, step.Send, step.State, state))
+		}
+	}
+	return
+}
+
+func matchEvents(expect []DialogExpect, events []SimEvent) bool {
+	if len(expect) != len(events) {
+		return false
+	}
+	for i := range expect {
+		if !expect[i].match(events[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+)
+
+const routingDialog = `name: Подменю и договор
+user:
+  name: Мария
+steps:
+  - event: start
+    state: greetings
+  - send: привет
+    expect:
+      - text: Главное меню
+        keyboard: [Подменю, Вложенное, Указать договор, Заявка, Закрыть]
+    state: start
+  - send: 3
+    expect:
+      - contains: договора
+  - send: A-42
+    expect:
+      - text: Договор A-42 сохранен
+      - text: Подменю
+        keyboard: [Назад, В конец]
+    state: sub
+`
+
+func loadRoutingMenus(t *testing.T) (*config.Conf, *botconfig_parser.Levels) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.yml")
+	if err := os.WriteFile(path, []byte(routingConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	menus, err := botconfig_parser.LoadLevels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &config.Conf{FilesDir: dir}, menus
+}
+
+func TestLoadDialogs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"routing.yml":  routingDialog,
+		"noname.yaml":  "steps:\n  - send: привет\n",
+		"readme.txt":   "не сценарий",
+		"sub/skip.yml": "steps: []\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dialogs, err := LoadDialogs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dialogs) != 2 {
+		t.Fatalf("loaded %d dialogs, want 2: %+v", len(dialogs), dialogs)
+	}
+	// имя по умолчанию - имя файла
+	if dialogs[0].Name != "noname.yaml" || dialogs[1].Name != "Подменю и договор" {
+		t.Errorf("names = %q, %q", dialogs[0].Name, dialogs[1].Name)
+	}
+	if dialogs[1].User == nil || dialogs[1].User.Name != "Мария" || len(dialogs[1].Steps) != 4 {
+		t.Errorf("dialog = %+v", dialogs[1])
+	}
+
+	if _, err := LoadDialogs(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Error("missing file loaded")
+	}
+	_ = os.WriteFile(filepath.Join(dir, "broken.yml"), []byte("steps: текст\n"), 0o644)
+	if _, err := LoadDialogs(dir); err == nil || !strings.Contains(err.Error(), "broken.yml") {
+		t.Errorf("broken dialog error = %v", err)
+	}
+}
+
+func TestRunDialog(t *testing.T) {
+	cnf, menus := loadRoutingMenus(t)
+
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "d.yml"), []byte(routingDialog), 0o644)
+	dialogs, err := LoadDialogs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := dialogs[0]
+
+	if diffs := RunDialog(cnf, menus, d); len(diffs) != 0 {
+		t.Fatalf("diffs for passing dialog:\n%s", strings.Join(diffs, "\n"))
+	}
+
+	// каждое расхождение сообщается отдельно
+	d.Steps[1].Expect[0].Keyboard = &[]string{"Подменю"}
+	d.Steps[3].State = "start"
+	diffs := RunDialog(cnf, menus, d)
+	if len(diffs) != 2 {
+		t.Fatalf("diffs = %q, want 2", diffs)
+	}
+	if !strings.Contains(diffs[0], "шаг 2 (привет): ответ бота отличается") || !strings.Contains(diffs[0], "[1] Подменю") {
+		t.Errorf("keyboard diff = %s", diffs[0])
+	}
+	if diffs[1] != "шаг 4 (A-42): ожидалось меню start, получено sub" {
+		t.Errorf("state diff = %s", diffs[1])
+	}
+
+	d.Steps[0].Event = "call"
+	if diffs := RunDialog(cnf, menus, d); len(diffs) != 1 || !strings.Contains(diffs[0], "неизвестное событие call") {
+		t.Errorf("unknown event diffs = %q", diffs)
+	}
+}
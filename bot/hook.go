@@ -1,48 +1,235 @@
 package bot
 
 import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
 	"connect-text-bot/internal/config"
 	"connect-text-bot/internal/connect/client"
 	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/transcript"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const eventUri = "/connect-push/receive/"
 
-func InitHooks(app *gin.Engine, cnf *config.Conf) {
+var (
+	// установлены ли хуки на всех линиях
+	hooksSet atomic.Bool
+
+	// изменение списка линий и снятие хуков не должны пересекаться
+	linesLock sync.Mutex
+)
+
+func InitHooks(app *gin.Engine, cnf *config.Conf, menus *botconfig_parser.MenuSet, archive *transcript.Archive) {
 	logger.Info("Init receiving endpoint...")
 
-	app.POST(eventUri, Receive)
+	app.POST(eventUri, hookAuth(cnf.Server), Receive)
+
+	hookAddr := hookAddress(cnf.Server)
 
 	logger.Info("Setup hooks on 1C-Connect...")
 
-	var err error
-	for _, lineID := range cnf.Line {
-		logger.Info("- hook for line", lineID)
-		connect := client.New(lineID, cnf.ConnectServer.Addr, cnf.Connect.Login, cnf.Connect.Password, cnf.GeneralSettings, cnf.SpecID)
+	linesLock.Lock()
+	defer linesLock.Unlock()
 
-		_, err = connect.SetHook(cnf.Server.Host + eventUri)
-		if err != nil {
+	for _, line := range cnf.Line {
+		logger.Info("- hook for line", line.ID, "with menu", line.BotConfig)
+		b := newBot(cnf, line, menus.Get(line.BotConfig), archive)
+
+		if _, err := b.connect.SetHook(hookAddr); err != nil {
 			logger.Crit("Error while setup hook:", err)
 		}
 
-		botsConnect[lineID] = Bot{
-			connect: connect,
+		botsConnect.set(line.ID, b)
+	}
+
+	hooksSet.Store(true)
+}
+
+// UpdateLines - применить список линий из перечитанного config.yml без перезапуска.
+// На новые линии устанавливается хук, с удалённых снимается, у изменённых пересоздаётся бот.
+// Линии, которые не удалось подключить, пропускаются и остаются в прежнем состоянии.
+// Хуки удалённых линий снимаются после обработки принятых ими сообщений, без блокировки списка линий
+func UpdateLines(cnf *config.Conf, lines []config.LineConfig, menus *botconfig_parser.MenuSet, archive *transcript.Archive) (diff LinesDiff) {
+	removed := updateLines(cnf, lines, menus, archive, &diff)
+
+	for _, b := range removed {
+		lineID := b.line.ID
+		logger.Info("- delete hook for line", lineID)
+		if !b.waitTasks(cnf.Workers.DrainTimeout) {
+			logger.Warning("Line", lineID, "removed before all accepted messages were processed in", cnf.Workers.DrainTimeout)
 		}
+		if _, err := b.connect.DeleteHook(); err != nil {
+			logger.Warning("Error while delete hook:", err)
+		}
+		diff.Removed = append(diff.Removed, lineID)
 	}
+	return diff
 }
 
-func DestroyHooks() {
-	logger.Info("Destroy hooks on 1C-Connect...")
+// updateLines - заменить ботов линий под блокировкой, возвращает ботов удалённых линий
+func updateLines(cnf *config.Conf, lines []config.LineConfig, menus *botconfig_parser.MenuSet, archive *transcript.Archive, diff *LinesDiff) (removed []Bot) {
+	linesLock.Lock()
+	defer linesLock.Unlock()
 
-	var err error
-	for line_id, b := range botsConnect {
-		_, err = b.connect.DeleteHook()
+	// после DestroyHooks бот останавливается, хуки не возвращаем
+	if !hooksSet.Load() {
+		return
+	}
+
+	hookAddr := hookAddress(cnf.Server)
+
+	current := make(map[uuid.UUID]config.LineConfig)
+	for _, line := range botsConnect.configs() {
+		current[line.ID] = line
+	}
+
+	for _, line := range lines {
+		old, exist := current[line.ID]
+		delete(current, line.ID)
+		if exist && old.Equal(line) {
+			continue
+		}
+
+		menu, err := menus.Load(line.BotConfig)
 		if err != nil {
-			logger.Warning("Error while delete hook:", err)
+			logger.Warning("Не корректный конфиг бота линии", line.ID, line.BotConfig, err)
+			diff.Failed = append(diff.Failed, line.ID)
+			continue
 		}
+		b := newBot(cnf, line, menu, archive)
 
-		delete(botsConnect, line_id)
+		if !exist {
+			logger.Info("- hook for new line", line.ID, "with menu", line.BotConfig)
+			if _, err := b.connect.SetHook(hookAddr); err != nil {
+				logger.Warning("Error while setup hook:", line.ID, err)
+				diff.Failed = append(diff.Failed, line.ID)
+				continue
+			}
+			diff.Added = append(diff.Added, line.ID)
+		} else {
+			logger.Info("- update line", line.ID, "with menu", line.BotConfig)
+			diff.Updated = append(diff.Updated, line.ID)
+		}
+
+		botsConnect.set(line.ID, b)
+	}
+
+	// оставшихся линий нет в новом списке, новые сообщения для них больше не принимаются
+	for lineID := range current {
+		if b, ok := botsConnect.remove(lineID); ok {
+			removed = append(removed, b)
+		}
+	}
+
+	menus.Retain((&config.Conf{Line: botsConnect.configs()}).BotConfigs())
+	return removed
+}
+
+// LinesDiff - как изменился список линий
+type LinesDiff struct {
+	Added   []uuid.UUID `json:"added,omitempty"`
+	Removed []uuid.UUID `json:"removed,omitempty"`
+	Updated []uuid.UUID `json:"updated,omitempty"`
+	// линии, которые не удалось подключить или изменить
+	Failed []uuid.UUID `json:"failed,omitempty"`
+}
+
+// Empty - список линий не изменился
+func (d LinesDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0 && len(d.Failed) == 0
+}
+
+func (d LinesDiff) String() string {
+	var parts []string
+	for _, p := range []struct {
+		name  string
+		lines []uuid.UUID
+	}{
+		{"добавлены", d.Added},
+		{"удалены", d.Removed},
+		{"изменены", d.Updated},
+		{"не подключены", d.Failed},
+	} {
+		if len(p.lines) == 0 {
+			continue
+		}
+		ids := make([]string, len(p.lines))
+		for i, id := range p.lines {
+			ids[i] = id.String()
+		}
+		parts = append(parts, p.name+" "+strings.Join(ids, ", "))
+	}
+	if len(parts) == 0 {
+		return "линии не изменились"
+	}
+	return "линии: " + strings.Join(parts, "; ")
+}
+
+// HooksReady - проверка что хуки установлены на всех линиях из config.yml
+func HooksReady(_ context.Context) error {
+	if !hooksSet.Load() {
+		return errors.New("хуки не установлены")
+	}
+	return nil
+}
+
+func DestroyHooks() {
+	logger.Info("Destroy hooks on 1C-Connect...")
+
+	linesLock.Lock()
+	defer linesLock.Unlock()
+
+	hooksSet.Store(false)
+
+	// принятые сообщения дожидается остановка пула обработчиков
+	for _, lineID := range botsConnect.lines() {
+		removeBot(lineID, 0)
+	}
+}
+
+// hookAddress - адрес, на который 1С-Коннект отправляет сообщения
+func hookAddress(srv config.Server) string {
+	hookAddr := srv.Host + eventUri
+	if srv.HookToken != "" {
+		hookAddr += "?" + url.Values{"token": {srv.HookToken}}.Encode()
+	}
+	return hookAddr
+}
+
+func newBot(cnf *config.Conf, line config.LineConfig, menu *botconfig_parser.Versioned, archive *transcript.Archive) Bot {
+	connect := client.New(line.ID, cnf.ConnectServer.Addr, cnf.Connect.Login, cnf.Connect.Password, cnf.GeneralSettings, line.SpecID)
+
+	return Bot{
+		connect:  transcript.Wrap(connect, archive, line.ID),
+		line:     line,
+		menu:     menu,
+		filesDir: line.FilesDir,
+		specID:   line.SpecID,
+		tasks:    &sync.WaitGroup{},
+	}
+}
+
+// removeBot - перестать обслуживать линию и снять с неё хук. Хук снимается после обработки
+// уже принятых сообщений линии, но не дольше wait
+func removeBot(lineID uuid.UUID, wait time.Duration) {
+	b, ok := botsConnect.remove(lineID)
+	if !ok {
+		return
+	}
+	if wait > 0 && !b.waitTasks(wait) {
+		logger.Warning("Line", lineID, "removed before all accepted messages were processed in", wait)
+	}
+	if _, err := b.connect.DeleteHook(); err != nil {
+		logger.Warning("Error while delete hook:", err)
 	}
 }
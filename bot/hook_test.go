@@ -0,0 +1,223 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/connecttest"
+
+	"github.com/google/uuid"
+)
+
+func addTestBot(t *testing.T) (uuid.UUID, *connecttest.Fake) {
+	t.Helper()
+	lineID := uuid.New()
+	fake := connecttest.New(lineID)
+	botsConnect.set(lineID, Bot{connect: fake, tasks: &sync.WaitGroup{}})
+	t.Cleanup(func() { botsConnect.remove(lineID) })
+	return lineID, fake
+}
+
+func TestRemoveBotWaitsAcceptedMessages(t *testing.T) {
+	lineID, fake := addTestBot(t)
+
+	b, ok := botsConnect.acquire(lineID)
+	if !ok {
+		t.Fatal("line not served")
+	}
+
+	removed := make(chan struct{})
+	go func() {
+		removeBot(lineID, time.Minute)
+		close(removed)
+	}()
+
+	// новые сообщения линии уже не принимаются
+	deadline := time.Now().Add(time.Second)
+	for {
+		b, ok := botsConnect.acquire(lineID)
+		if !ok {
+			break
+		}
+		b.tasks.Done()
+		if time.Now().After(deadline) {
+			t.Fatal("removed line still accepts messages")
+		}
+	}
+
+	select {
+	case <-removed:
+		t.Fatal("hook deleted before accepted message was processed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if calls := fake.CallsTo("DeleteHook"); len(calls) != 0 {
+		t.Fatalf("DeleteHook called while message is processed: %v", calls)
+	}
+
+	b.tasks.Done()
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		t.Fatal("removeBot did not return after message was processed")
+	}
+	if calls := fake.CallsTo("DeleteHook"); len(calls) != 1 {
+		t.Fatalf("DeleteHook calls = %d, want 1", len(calls))
+	}
+}
+
+func TestRemoveBotWaitTimeout(t *testing.T) {
+	lineID, fake := addTestBot(t)
+
+	if _, ok := botsConnect.acquire(lineID); !ok {
+		t.Fatal("line not served")
+	}
+
+	start := time.Now()
+	removeBot(lineID, 20*time.Millisecond)
+	if time.Since(start) > time.Second {
+		t.Fatal("removeBot waited longer than timeout")
+	}
+	if calls := fake.CallsTo("DeleteHook"); len(calls) != 1 {
+		t.Fatalf("DeleteHook calls = %d, want 1", len(calls))
+	}
+}
+
+func TestHookAddress(t *testing.T) {
+	srv := config.Server{Host: "https://bot.example.com"}
+	if got := hookAddress(srv); got != "https://bot.example.com"+eventUri {
+		t.Errorf("hookAddress() = %q", got)
+	}
+	srv.HookToken = "a b&c"
+	if got := hookAddress(srv); got != "https://bot.example.com"+eventUri+"?token=a+b%26c" {
+		t.Errorf("hookAddress() with token = %q", got)
+	}
+}
+
+func TestBotsConcurrentUpdate(t *testing.T) {
+	lines := make([]uuid.UUID, 20)
+	for i := range lines {
+		lines[i] = uuid.New()
+	}
+	t.Cleanup(func() {
+		for _, lineID := range lines {
+			botsConnect.remove(lineID)
+		}
+	})
+
+	// линии добавляются и удаляются пока обрабатываются сообщения
+	var wg sync.WaitGroup
+	for _, lineID := range lines {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			botsConnect.set(lineID, Bot{connect: connecttest.New(lineID), tasks: &sync.WaitGroup{}})
+			botsConnect.remove(lineID)
+			botsConnect.set(lineID, Bot{connect: connecttest.New(lineID), tasks: &sync.WaitGroup{}})
+		}()
+		go func() {
+			defer wg.Done()
+			botsConnect.get(lineID)
+			botsConnect.lines()
+		}()
+	}
+	wg.Wait()
+
+	served := botsConnect.lines()
+	for _, lineID := range lines {
+		if !slices.Contains(served, lineID) {
+			t.Fatalf("line %s not served", lineID)
+		}
+	}
+}
+
+func TestUpdateLines(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	connect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+	}))
+	defer connect.Close()
+	called := func(request string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := 0
+		for _, r := range requests {
+			if r == request {
+				n++
+			}
+		}
+		return n
+	}
+
+	botConfig := filepath.Join(t.TempDir(), "bot.yml")
+	writeTestFile(t, botConfig, routingConfig)
+	menus := botconfig_parser.InitMenuSet([]string{botConfig})
+
+	cnf := &config.Conf{}
+	cnf.ConnectServer.Addr = connect.URL
+	cnf.Workers.DrainTimeout = time.Minute
+
+	hooksSet.Store(true)
+	first, second := uuid.New(), uuid.New()
+	t.Cleanup(func() {
+		hooksSet.Store(false)
+		botsConnect.remove(first)
+		botsConnect.remove(second)
+	})
+
+	lines := []config.LineConfig{{ID: first, BotConfig: botConfig}, {ID: second, BotConfig: botConfig}}
+	diff := UpdateLines(cnf, lines, menus, nil)
+	if len(diff.Added) != 2 || called("POST /v1/hook/") != 2 {
+		t.Fatalf("diff = %+v, requests = %v", diff, requests)
+	}
+
+	// удаляемая линия ещё обрабатывает сообщение
+	b, ok := botsConnect.acquire(first)
+	if !ok {
+		t.Fatal("line not served")
+	}
+	done := make(chan LinesDiff)
+	go func() { done <- UpdateLines(cnf, lines[1:], menus, nil) }()
+
+	deadline := time.Now().Add(time.Second)
+	for slices.ContainsFunc(botsConnect.configs(), func(l config.LineConfig) bool { return l.ID == first }) {
+		if time.Now().After(deadline) {
+			t.Fatal("removed line still served")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// пока хук ждёт обработки сообщения, список линий не заблокирован
+	if !linesLock.TryLock() {
+		t.Fatal("linesLock held while draining removed line")
+	}
+	linesLock.Unlock()
+	if n := called("DELETE /v1/hook/bot/" + first.String() + "/"); n != 0 {
+		t.Fatal("hook deleted before accepted message was processed")
+	}
+
+	b.tasks.Done()
+	select {
+	case diff = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("UpdateLines did not return after message was processed")
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != first || called("DELETE /v1/hook/bot/"+first.String()+"/") != 1 {
+		t.Fatalf("diff = %+v, requests = %v", diff, requests)
+	}
+
+	if diff := UpdateLines(cnf, lines[1:], menus, nil); !diff.Empty() {
+		t.Errorf("same lines: %+v", diff)
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/metrics"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// отправить на хук текстовое сообщение пользователя и дождаться ответа бота
+func (s *adminServer) receive(t *testing.T, userID, messageID uuid.UUID, text string) {
+	t.Helper()
+	sent := len(s.fake.CallsTo("Send"))
+
+	body, _ := json.Marshal(messages.Message{
+		LineID:        s.lineID,
+		UserID:        userID,
+		MessageID:     messageID,
+		MessageType:   messages.MESSAGE_TEXT,
+		MessageAuthor: &userID,
+		MessageTime:   "1",
+		Text:          text,
+	})
+	if w := s.do(http.MethodPost, eventUri, string(body)); w.Code != http.StatusOK {
+		t.Fatalf("receive %q: status = %d", text, w.Code)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(s.fake.CallsTo("Send")) == sent {
+		if time.Now().After(deadline) {
+			t.Fatalf("no answer to %q", text)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMessageMetrics(t *testing.T) {
+	s := newAdminServer(t)
+	user := uuid.New()
+	s.setState(t, user, database.START)
+
+	textType := metrics.MessagesReceived.WithLabelValues("1")
+	received := testutil.ToFloat64(textType)
+	duplicates := testutil.ToFloat64(metrics.DuplicateMessages)
+	clicks := func(menu, button string) float64 {
+		return testutil.ToFloat64(metrics.ButtonClicks.WithLabelValues(menu, button))
+	}
+	subClicks, backClicks := clicks(database.START, "1"), clicks("sub", "1")
+
+	messageID := uuid.New()
+	s.receive(t, user, messageID, "Подменю")
+	if got := testutil.ToFloat64(textType) - received; got != 1 {
+		t.Errorf("messages received += %v, want 1", got)
+	}
+	if got := clicks(database.START, "1") - subClicks; got != 1 {
+		t.Errorf("clicks of start/1 += %v, want 1", got)
+	}
+
+	// повторная доставка считается, но не обрабатывается
+	if w := s.do(http.MethodPost, eventUri, `{"line_id":"`+s.lineID.String()+`","user_id":"`+user.String()+`","message_id":"`+messageID.String()+`","message_type":1,"message_time":"1","text":"Подменю"}`); w.Code != http.StatusOK {
+		t.Fatalf("duplicate: status = %d", w.Code)
+	}
+	if got := testutil.ToFloat64(metrics.DuplicateMessages) - duplicates; got != 1 {
+		t.Errorf("duplicates += %v, want 1", got)
+	}
+
+	// произвольный текст не нажимает кнопку
+	s.receive(t, user, uuid.New(), "Привет")
+	s.receive(t, user, uuid.New(), "Назад")
+	if got := clicks(database.START, "1") - subClicks; got != 1 {
+		t.Errorf("clicks of start/1 += %v after other messages, want 1", got)
+	}
+	if got := clicks("sub", "1") - backClicks; got != 1 {
+		t.Errorf("clicks of sub/1 += %v, want 1", got)
+	}
+}
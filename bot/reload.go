@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/transcript"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/fsnotify.v1"
+)
+
+type (
+	// Reloader - применение изменений config.yml и конфигов меню без перезапуска
+	Reloader struct {
+		configFile string
+		botConfig  string
+
+		cnf *config.Conf
+		// последний примененный config.yml, с ним сравнивается перечитанный
+		last    *config.Conf
+		menus   *botconfig_parser.MenuSet
+		archive *transcript.Archive
+
+		// вызывается после перечитывания, например чтобы следить за каталогами меню новых линий
+		afterReload func()
+
+		// перечитывание из наблюдателя, по сигналу и из /admin не должны пересекаться
+		lock sync.Mutex
+	}
+
+	// ReloadReport - что изменилось при перечитывании конфигов
+	ReloadReport struct {
+		Lines *LinesDiff                 `json:"lines,omitempty"`
+		Menus []botconfig_parser.Changes `json:"menus,omitempty"`
+		// измененные настройки config.yml
+		Settings []string `json:"settings,omitempty"`
+		// измененные настройки, которые применятся только после перезапуска
+		RestartRequired []string `json:"restart_required,omitempty"`
+		Errors          []string `json:"errors,omitempty"`
+	}
+)
+
+func NewReloader(configFile, botConfig string, cnf *config.Conf, menus *botconfig_parser.MenuSet, archive *transcript.Archive, afterReload func()) *Reloader {
+	return &Reloader{
+		configFile:  configFile,
+		botConfig:   botConfig,
+		cnf:         cnf,
+		last:        cnf,
+		menus:       menus,
+		archive:     archive,
+		afterReload: afterReload,
+	}
+}
+
+// Reload - перечитать все конфиги меню и список линий из config.yml
+func (r *Reloader) Reload() ReloadReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var report ReloadReport
+	report.addMenus(r.menus.ReloadAll())
+	r.reloadLines(&report)
+
+	r.done(report)
+	return report
+}
+
+// Apply - применить изменения файлов changed: config.yml или файлов меню
+func (r *Reloader) Apply(changed []string) ReloadReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	configPath, _ := filepath.Abs(r.configFile)
+
+	var (
+		report        ReloadReport
+		configChanged bool
+		menuFiles     []string
+	)
+	for _, name := range changed {
+		if path, err := filepath.Abs(name); err == nil && path == configPath {
+			configChanged = true
+			continue
+		}
+		menuFiles = append(menuFiles, name)
+	}
+
+	if len(menuFiles) > 0 {
+		report.addMenus(r.menus.Update(menuFiles...))
+	}
+	if configChanged {
+		r.reloadLines(&report)
+	}
+
+	r.done(report)
+	return report
+}
+
+// reloadLines - применить список линий из config.yml, остальные настройки только сравниваются
+func (r *Reloader) reloadLines(report *ReloadReport) {
+	next := &config.Conf{}
+	if err := config.LoadConfig(r.configFile, next); err != nil {
+		report.Errors = append(report.Errors, "config.yml не применен: "+err.Error())
+		return
+	}
+	// пустой список скорее всего означает недописанный файл, снимать все хуки не стоит
+	if len(next.Line) == 0 {
+		report.Errors = append(report.Errors, "в config.yml не указаны линии, список линий не изменен")
+		return
+	}
+	next.BotConfig = r.botConfig
+	next.SetLineDefaults()
+
+	report.Settings = config.Diff(r.last, next)
+	for _, s := range report.Settings {
+		if !appliedOnReload(s) {
+			report.RestartRequired = append(report.RestartRequired, s)
+		}
+	}
+
+	diff := UpdateLines(r.cnf, next.Line, r.menus, r.archive)
+	report.Lines = &diff
+	r.last = next
+}
+
+// appliedOnReload - применяется ли настройка config.yml без перезапуска.
+// Применяется только список линий, общие настройки линий попадают в него через SetLineDefaults
+func appliedOnReload(setting string) bool {
+	switch setting {
+	case "line", "bot_config", "files_dir", "spec_id":
+		return true
+	}
+	return false
+}
+
+func (r *Reloader) done(report ReloadReport) {
+	if r.afterReload != nil {
+		r.afterReload()
+	}
+
+	if len(report.Errors) > 0 {
+		logger.Warning("Reload:", report)
+		return
+	}
+	logger.Info("Reload:", report)
+}
+
+func (report *ReloadReport) addMenus(changes []botconfig_parser.Changes) {
+	for _, ch := range changes {
+		report.Menus = append(report.Menus, ch)
+		if ch.Error != "" {
+			report.Errors = append(report.Errors, ch.Path+": "+ch.Error)
+		}
+	}
+}
+
+func (report ReloadReport) String() string {
+	var parts []string
+	if report.Lines != nil {
+		parts = append(parts, report.Lines.String())
+	}
+	for _, ch := range report.Menus {
+		if ch.Error == "" {
+			parts = append(parts, ch.String())
+		}
+	}
+	if len(report.Settings) > 0 {
+		parts = append(parts, "изменены настройки config.yml: "+strings.Join(report.Settings, ", "))
+	}
+	if len(report.RestartRequired) > 0 {
+		parts = append(parts, "применятся после перезапуска: "+strings.Join(report.RestartRequired, ", "))
+	}
+	parts = append(parts, report.Errors...)
+	if len(parts) == 0 {
+		return "изменений нет"
+	}
+	return strings.Join(parts, "; ")
+}
+
+// CollectChanges - накапливать изменения файлов из events и передавать их в apply,
+// когда файлы не менялись delay. Завершается когда events закрыт
+func CollectChanges(events <-chan fsnotify.Event, delay time.Duration, apply func(changed map[string]fsnotify.Op)) {
+	changed := make(map[string]fsnotify.Op)
+	var settle <-chan time.Time
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			logger.Event(event)
+			if event.Name == "" {
+				continue
+			}
+			changed[event.Name] |= event.Op
+			settle = time.After(delay)
+		case <-settle:
+			settle = nil
+			apply(changed)
+			changed = make(map[string]fsnotify.Op)
+		}
+	}
+}
+
+// reloadConfigs - перечитать конфиги по запросу, в ответе что изменилось
+func reloadConfigs(r *Reloader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := r.Reload()
+		if len(report.Errors) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, report)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// конфиги для Reloader: config.yml и меню routingConfig
+func newTestReloader(t *testing.T) (*Reloader, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	botConfig := filepath.Join(dir, "bot.yml")
+	writeTestFile(t, configFile, "line:\n  - 00000000-0000-0000-0000-00000000000a\n")
+	writeTestFile(t, botConfig, routingConfig)
+
+	cnf := &config.Conf{}
+	if err := config.LoadConfig(configFile, cnf); err != nil {
+		t.Fatal(err)
+	}
+	cnf.BotConfig = botConfig
+	cnf.SetLineDefaults()
+
+	reloaded := 0
+	r := NewReloader(configFile, botConfig, cnf, botconfig_parser.InitMenuSet(cnf.BotConfigs()), nil, func() { reloaded++ })
+	t.Cleanup(func() {
+		if reloaded == 0 {
+			t.Error("afterReload not called")
+		}
+	})
+	return r, configFile, botConfig
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloaderApplyMenu(t *testing.T) {
+	r, _, botConfig := newTestReloader(t)
+
+	writeTestFile(t, botConfig, strings.Replace(routingConfig, `"Подменю"`+"\n    buttons", `"Новое подменю"`+"\n    buttons", 1))
+	report := r.Apply([]string{botConfig})
+	if len(report.Errors) != 0 || report.Lines != nil {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(report.Menus) != 1 || strings.Join(report.Menus[0].Changed, ",") != "sub" {
+		t.Fatalf("menus = %+v", report.Menus)
+	}
+
+	// ошибка в меню попадает в отчет
+	writeTestFile(t, botConfig, "menus: текст\n")
+	report = r.Apply([]string{botConfig})
+	if len(report.Errors) != 1 || !strings.HasPrefix(report.Errors[0], botConfig) {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+}
+
+func TestReloaderConfigErrors(t *testing.T) {
+	r, configFile, _ := newTestReloader(t)
+
+	// config.yml без линий не снимает хуки
+	writeTestFile(t, configFile, "files_dir: ./files\n")
+	report := r.Apply([]string{configFile})
+	if len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "не указаны линии") || report.Lines != nil {
+		t.Fatalf("report = %+v", report)
+	}
+
+	writeTestFile(t, configFile, "server: текст\n")
+	report = r.Reload()
+	if len(report.Errors) == 0 || !strings.Contains(report.String(), "config.yml не применен") {
+		t.Fatalf("report = %+v", report)
+	}
+	if len(report.Menus) != 1 || !report.Menus[0].Empty() {
+		t.Errorf("menus = %+v", report.Menus)
+	}
+}
+
+func TestReloaderSettings(t *testing.T) {
+	r, configFile, _ := newTestReloader(t)
+
+	writeTestFile(t, configFile, "server:\n  listen: :8081\nspec_id: 00000000-0000-0000-0000-00000000000b\nline:\n  - 00000000-0000-0000-0000-00000000000a\n")
+	report := r.Apply([]string{configFile})
+	if len(report.Errors) != 0 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+	if got := strings.Join(report.Settings, ","); got != "server.listen,spec_id,line" {
+		t.Errorf("settings = %s", got)
+	}
+	if got := strings.Join(report.RestartRequired, ","); got != "server.listen" {
+		t.Errorf("restart_required = %s", got)
+	}
+	if !strings.Contains(report.String(), "применятся после перезапуска: server.listen") {
+		t.Errorf("string = %s", report.String())
+	}
+
+	// перечитанный config.yml сравнивается с последним примененным
+	report = r.Reload()
+	if len(report.Settings) != 0 || len(report.RestartRequired) != 0 {
+		t.Errorf("same config: %+v", report)
+	}
+}
+
+func TestCollectChanges(t *testing.T) {
+	events := make(chan fsnotify.Event)
+	applied := make(chan map[string]fsnotify.Op, 2)
+	done := make(chan struct{})
+	go func() {
+		CollectChanges(events, 50*time.Millisecond, func(changed map[string]fsnotify.Op) { applied <- changed })
+		close(done)
+	}()
+
+	// серия изменений применяется один раз, после того как файлы перестали меняться
+	events <- fsnotify.Event{Name: "bot.yml", Op: fsnotify.Create}
+	events <- fsnotify.Event{Name: "bot.yml", Op: fsnotify.Write}
+	events <- fsnotify.Event{Name: ""}
+	events <- fsnotify.Event{Name: "config.yml", Op: fsnotify.Write}
+
+	select {
+	case changed := <-applied:
+		want := map[string]fsnotify.Op{"bot.yml": fsnotify.Create | fsnotify.Write, "config.yml": fsnotify.Write}
+		if !maps.Equal(changed, want) {
+			t.Errorf("changed = %v, want %v", changed, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("changes not applied")
+	}
+	select {
+	case changed := <-applied:
+		t.Fatalf("changes applied twice: %v", changed)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(events)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("CollectChanges did not return after events closed")
+	}
+}
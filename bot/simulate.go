@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/cache"
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/connect/connecttest"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/connect/requests"
+	"connect-text-bot/internal/connect/response"
+	"connect-text-bot/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// SimEvent - действие бота, записанное заглушкой API 1С-Коннект
+type SimEvent struct {
+	// text, file, drop_keyboard, close, redirect, reroute, appoint_spec, ticket
+	Action string
+	// текст сообщения, подпись к файлу, линия или специалист для перевода, тема заявки
+	Text string
+	// имя отправленного файла
+	File     string
+	Keyboard [][]requests.KeyboardKey
+}
+
+// KeyboardTexts - тексты кнопок клавиатуры по порядку
+func (e SimEvent) KeyboardTexts() []string {
+	texts := make([]string, 0)
+	for _, row := range e.Keyboard {
+		for _, k := range row {
+			texts = append(texts, k.Text)
+		}
+	}
+	return texts
+}
+
+func (e SimEvent) String() string {
+	var str string
+	switch e.Action {
+	case "text":
+		str = "бот: " + strings.ReplaceAll(e.Text, "\n", "\n     ")
+	case "file":
+		str = "бот: [файл " + e.File + "]"
+		if e.Text != "" {
+			str += " " + e.Text
+		}
+	default:
+		str = "~ " + e.Action
+		if e.Text != "" {
+			str += " " + e.Text
+		}
+	}
+
+	for _, row := range e.Keyboard {
+		keys := make([]string, 0, len(row))
+		for _, k := range row {
+			id := k.ID
+			if id == "" {
+				id = " "
+			}
+			keys = append(keys, fmt.Sprintf("[%s] %s", id, k.Text))
+		}
+		str += "\n    " + strings.Join(keys, "  ")
+	}
+	return str
+}
+
+// действия бота по записанным вызовам API, запросы данных пропускаются
+func simEvents(calls []connecttest.Call) (events []SimEvent) {
+	for _, c := range calls {
+		e := SimEvent{Text: c.Text}
+		if c.Keyboard != nil {
+			e.Keyboard = *c.Keyboard
+		}
+
+		switch c.Method {
+		case "Send":
+			e.Action = "text"
+		case "SendFile":
+			e.Action, e.File = "file", c.FileName
+		case "Start", "DropKeyboard":
+			e.Action = "drop_keyboard"
+		case "CloseTreatment":
+			e.Action = "close"
+		case "RerouteTreatment":
+			e.Action = "redirect"
+		case "Reroute":
+			e.Action, e.Text = "reroute", c.ID.String()
+		case "AppointSpec":
+			e.Action, e.Text = "appoint_spec", c.ID.String()
+		case "CreateTicket":
+			e.Action = "ticket"
+		default:
+			continue
+		}
+		events = append(events, e)
+	}
+	return
+}
+
+// stubTickets - заглушка SOAP сервиса УС, заявка регистрируется всегда успешно
+type stubTickets struct {
+	fake *connecttest.Fake
+}
+
+func (t stubTickets) CreateTicket(_ context.Context, userID, _ uuid.UUID, ticket database.Ticket) (map[string]string, error) {
+	t.fake.Record(connecttest.Call{Method: "CreateTicket", UserID: userID, Text: ticket.Theme})
+	return map[string]string{"ServiceRequestID": uuid.NewString()}, nil
+}
+
+// simSession - диалог одного пользователя с ботом без подключения к 1С-Коннект и УС
+type simSession struct {
+	fake    *connecttest.Fake
+	cacheDB database.StateStore
+	cnf     *config.Conf
+	menus   *botconfig_parser.Levels
+
+	lineID uuid.UUID
+	userID uuid.UUID
+}
+
+// пользователь по умолчанию, данные используются в шаблонах
+var simUser = response.User{Name: "Иван", Surname: "Тестов", Patronymic: "Иванович", Email: "test@example.com"}
+
+func newSimSession(cnf *config.Conf, menus *botconfig_parser.Levels, user response.User) *simSession {
+	s := &simSession{
+		cacheDB: database.ConnectInMemoryCache(database.DEFAULT_STATE_LIFETIME),
+		cnf:     cnf,
+		menus:   menus,
+		lineID:  uuid.MustParse("00000000-0000-0000-0000-00000000000a"),
+		userID:  uuid.MustParse("00000000-0000-0000-0000-00000000000b"),
+	}
+
+	s.fake = connecttest.New(s.lineID)
+	s.fake.Subscriber = user
+	s.fake.Specialists = response.Users{
+		{UserID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Name: "Петр", Surname: "Специалистов"},
+		{UserID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Name: "Анна", Surname: "Консультантова"},
+	}
+	typeID := uuid.MustParse("00000000-0000-0000-0000-0000000000b1")
+	s.fake.TicketData = response.GetTicketDataResponse{
+		Kinds: []response.TicketDataKind{
+			{ID: uuid.MustParse("00000000-0000-0000-0000-0000000000a1"), Name: "Консультация", Types: []uuid.UUID{typeID}, Lines: []uuid.UUID{s.lineID}},
+		},
+		Types: []response.TicketDataType{
+			{ID: typeID, Name: "Запрос на обслуживание"},
+		},
+	}
+
+	// все специалисты из меню свободны
+	menus.WalkButtons(func(_ string, b *botconfig_parser.Button) {
+		if b.AppointSpecButton != nil {
+			s.fake.AvailableSpecs = append(s.fake.AvailableSpecs, *b.AppointSpecButton)
+		}
+		if b.AppointRandomSpecFromListButton != nil {
+			s.fake.AvailableSpecs = append(s.fake.AvailableSpecs, *b.AppointRandomSpecFromListButton...)
+		}
+	})
+	return s
+}
+
+func (s *simSession) Close() error {
+	return s.cacheDB.Close()
+}
+
+// отправить сообщение от пользователя, возвращает действия бота и новое состояние
+func (s *simSession) send(messageType messages.MessageType, text string) ([]SimEvent, string, error) {
+	s.fake.Reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), PROCESS_TIMEOUT)
+	defer cancel()
+
+	chatState := cache.GetState(s.fake, ctx, s.cacheDB, s.userID, s.lineID)
+	md := MultiData{
+		cacheDB:   s.cacheDB,
+		tickets:   stubTickets{fake: s.fake},
+		cnf:       s.cnf,
+		menu:      s.menus,
+		bot:       Bot{connect: s.fake, filesDir: s.cnf.FilesDir, specID: s.cnf.SpecID},
+		chatState: &chatState,
+		msg: messages.Message{
+			LineID:        s.lineID,
+			UserID:        s.userID,
+			MessageID:     uuid.New(),
+			MessageType:   messageType,
+			MessageAuthor: &s.userID,
+			Text:          text,
+		},
+	}
+
+	newState, err := processMessage(ctx, &md)
+	if errState := md.chatState.ChangeCacheState(s.cacheDB, s.userID, s.lineID, newState, md.menu.Version); errState != nil && err == nil {
+		err = errState
+	}
+	return simEvents(s.fake.Calls()), newState, err
+}
+
+// Simulate - диалог с ботом в терминале, вместо 1С-Коннект используется заглушка выводящая действия бота
+func Simulate(cnf *config.Conf, menus *botconfig_parser.Levels, in io.Reader, out io.Writer) error {
+	s := newSimSession(cnf, menus, simUser)
+	defer s.Close()
+
+	send := func(messageType messages.MessageType, text string) {
+		events, newState, err := s.send(messageType, text)
+		for _, e := range events {
+			fmt.Fprintln(out, e)
+		}
+		if err != nil {
+			fmt.Fprintln(out, "! ошибка:", err)
+		}
+		fmt.Fprintf(out, "  [%s]\n", newState)
+	}
+
+	fmt.Fprintln(out, "Введите сообщение от имени пользователя. Команды:")
+	fmt.Fprintln(out, "  /start - пользователь открыл обращение")
+	fmt.Fprintln(out, "  /close - специалист закрыл обращение")
+	fmt.Fprintln(out, "  /file <имя> - пользователь отправил файл")
+	fmt.Fprintln(out, "  /state - текущее состояние пользователя")
+	fmt.Fprintln(out, "  /reset - сбросить состояние")
+	fmt.Fprintln(out, "  /quit - выход")
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+
+		switch cmd, arg, _ := strings.Cut(line, " "); cmd {
+		case "":
+		case "/quit", "/exit":
+			return nil
+		case "/start":
+			send(messages.MESSAGE_TREATMENT_START_BY_USER, "")
+		case "/close":
+			send(messages.MESSAGE_TREATMENT_CLOSE, "")
+		case "/file":
+			send(messages.MESSAGE_FILE, arg)
+		case "/state":
+			chatState, err := cache.LoadState(s.cacheDB, s.userID, s.lineID)
+			if err != nil {
+				fmt.Fprintln(out, "! ошибка:", err)
+				continue
+			}
+			fmt.Fprintf(out, "  текущее: %s, предыдущее: %s, история: %v\n", chatState.CurrentState, chatState.PreviousState, chatState.HistoryState)
+			if len(chatState.Vars) != 0 {
+				fmt.Fprintf(out, "  переменные: %v\n", chatState.Vars)
+			}
+		case "/reset":
+			if err := cache.DeleteState(s.cacheDB, s.userID, s.lineID); err != nil {
+				fmt.Fprintln(out, "! ошибка:", err)
+			}
+		default:
+			send(messages.MESSAGE_TEXT, line)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+)
+
+func TestSimulate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot.yml")
+	if err := os.WriteFile(path, []byte(routingConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	menus, err := botconfig_parser.LoadLevels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := strings.NewReader("/start\nПривет\n3\nA-42\n/state\n/reset\n/state\n/quit\nне обработается\n")
+	var out strings.Builder
+	if err := Simulate(&config.Conf{FilesDir: dir}, menus, in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"  [greetings]\n",
+		"бот: Главное меню\n    [1] Подменю",
+		"бот: Номер договора?",
+		"  [wait_send_menu]\n",
+		"бот: Договор A-42 сохранен",
+		"текущее: sub",
+		"contract:A-42",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output has no %q:\n%s", want, got)
+		}
+	}
+	// после /reset состояния нет, после /quit ввод не читается
+	if !strings.Contains(got, "! ошибка: entry not found") || strings.Contains(got, "не обработается") {
+		t.Errorf("unexpected output:\n%s", got)
+	}
+}
@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/cache"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/database"
+)
+
+// routingConfig без меню sub
+func withoutSub(t *testing.T, version uint64) *botconfig_parser.Levels {
+	t.Helper()
+	config := routingConfig[:strings.Index(routingConfig, "  sub:")]
+	config = strings.ReplaceAll(config, "goto: sub", "goto: start")
+	path := filepath.Join(t.TempDir(), "bot.yml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	menus, err := botconfig_parser.LoadLevels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	menus.Version = version
+	return menus
+}
+
+func TestStateVanished(t *testing.T) {
+	t.Run("menu updated", func(t *testing.T) {
+		s := newTestSession(t)
+		s.menus.Version = 1
+		sendExpect(t, s, messages.MESSAGE_TEXT, "1", "sub")
+
+		// пока пользователь в sub, меню sub удалили из конфига
+		s.menus = withoutSub(t, 2)
+		events := sendExpect(t, s, messages.MESSAGE_TEXT, "1", database.GREETINGS)
+		if !slices.Contains(eventTexts(events), s.menus.ErrorMessages.MenuUpdated) {
+			t.Errorf("menu update not reported: %v", events)
+		}
+		if got := lastKeyboard(events); len(got) == 0 || got[0] != "Подменю" {
+			t.Errorf("keyboard = %v, want start", got)
+		}
+	})
+
+	t.Run("unknown state", func(t *testing.T) {
+		s := newTestSession(t)
+		s.menus = withoutSub(t, 1)
+
+		// в хранилище состояние, которого нет в той же версии меню
+		chatState, err := cache.LoadState(s.cacheDB, s.userID, s.lineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chatState.CurrentState, chatState.MenuVersion = "sub", 1
+		if err := chatState.ChangeCache(s.cacheDB, s.userID, s.lineID); err != nil {
+			t.Fatal(err)
+		}
+
+		events := sendExpect(t, s, messages.MESSAGE_TEXT, "1", database.GREETINGS)
+		if !slices.Contains(eventTexts(events), s.menus.ErrorMessages.CommandUnknown) {
+			t.Errorf("unknown command not reported: %v", events)
+		}
+	})
+}
+
+func TestMenuVersionPersisted(t *testing.T) {
+	s := newTestSession(t)
+	s.menus.Version = 1
+	sendExpect(t, s, messages.MESSAGE_TEXT, "1", "sub")
+
+	state := func() cache.Chat {
+		t.Helper()
+		chatState, err := cache.LoadState(s.cacheDB, s.userID, s.lineID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return chatState
+	}
+	if got := state().MenuVersion; got != 1 {
+		t.Fatalf("menu version = %d, want 1", got)
+	}
+
+	// состояние не изменилось, но меню обновилось
+	s.menus.Version = 2
+	sendExpect(t, s, messages.MESSAGE_TEXT, "неизвестная команда", "sub")
+	if got := state(); got.MenuVersion != 2 || got.CurrentState != "sub" || got.PreviousState != database.START {
+		t.Fatalf("state = %s after %s on version %d, want version 2", got.CurrentState, got.PreviousState, got.MenuVersion)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"connect-text-bot/bot"
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+)
+
+// dialogTest - прогнать сценарии диалогов через бота, код возврата 1 если ответы бота отличаются
+func dialogTest(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	var (
+		configFile = fs.String("config", "./config/config.yml", "Usage: -config=<config_file> (used for files_dir)")
+		botConfig  = fs.String("bot", "./config/bot.yml", "Usage: -bot=<botConfig_file>")
+		filesDir   = fs.String("files_dir", "", "Usage: -files_dir=<dir> (overrides files_dir from config)")
+		verbose    = fs.Bool("v", false, "Print bot logs")
+	)
+	_ = fs.Parse(args)
+
+	// логи бота мешают читать результат
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{"./config/tests"}
+	}
+
+	cnf := &config.Conf{}
+	if err := config.LoadConfig(*configFile, cnf); err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось прочитать config.yml, используются настройки по умолчанию:", err)
+	}
+	if *filesDir != "" {
+		cnf.FilesDir = *filesDir
+	}
+	if cnf.FilesDir == "" {
+		cnf.FilesDir = "./"
+	}
+	cnf.BotConfig = *botConfig
+
+	menus, err := botconfig_parser.LoadLevels(*botConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintf(os.Stderr, "\nИсправьте ошибки, подробнее: validate --bot=%s\n", *botConfig)
+		return 1
+	}
+
+	var dialogs []bot.Dialog
+	for _, p := range paths {
+		d, err := bot.LoadDialogs(p)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		dialogs = append(dialogs, d...)
+	}
+
+	failed := 0
+	for _, d := range dialogs {
+		diffs := bot.RunDialog(cnf, menus, d)
+		if len(diffs) == 0 {
+			fmt.Printf("ok   %s (%s)\n", d.Name, d.Path)
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %s (%s)\n", d.Name, d.Path)
+		for _, diff := range diffs {
+			fmt.Println(diff)
+		}
+	}
+
+	fmt.Printf("\nСценариев: %d, с ошибками: %d\n", len(dialogs), failed)
+	if failed != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"connect-text-bot/internal/botconfig_parser"
+)
+
+// graph - вывести схему переходов между меню в формате DOT или Mermaid
+func graph(args []string) int {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	var (
+		botConfig = fs.String("bot", "./config/bot.yml", "Usage: -bot=<botConfig_file>")
+		format    = fs.String("format", "dot", "Usage: -format=dot|mermaid")
+		output    = fs.String("out", "", "Usage: -out=<file> (stdout by default)")
+	)
+	_ = fs.Parse(args)
+
+	levels, err := botconfig_parser.LoadLevels(*botConfig)
+	// конфиг не прочитан или не разобран
+	if levels == nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// меню не прошло проверку, но схема полезна и для конфига с ошибками, поэтому только предупреждаем
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Конфиг содержит ошибки, проверьте его командой validate:")
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	var out string
+	switch *format {
+	case "dot":
+		out = levels.Graph().DOT()
+	case "mermaid":
+		out = levels.Graph().Mermaid()
+	default:
+		fmt.Fprintln(os.Stderr, "Неизвестный формат:", *format)
+		return 1
+	}
+
+	if *output == "" {
+		fmt.Print(out)
+		return 0
+	}
+	if err := os.WriteFile(*output, []byte(out), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"connect-text-bot/bot"
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+)
+
+// simulate - диалог с ботом в терминале без подключения к 1С-Коннект
+func simulate(args []string) int {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	var (
+		configFile = fs.String("config", "./config/config.yml", "Usage: -config=<config_file> (used for files_dir)")
+		botConfig  = fs.String("bot", "./config/bot.yml", "Usage: -bot=<botConfig_file>")
+		filesDir   = fs.String("files_dir", "", "Usage: -files_dir=<dir> (overrides files_dir from config)")
+	)
+	_ = fs.Parse(args)
+
+	cnf := &config.Conf{}
+	if err := config.LoadConfig(*configFile, cnf); err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось прочитать config.yml, используются настройки по умолчанию:", err)
+	}
+	if *filesDir != "" {
+		cnf.FilesDir = *filesDir
+	}
+	if cnf.FilesDir == "" {
+		cnf.FilesDir = "./"
+	}
+	cnf.BotConfig = *botConfig
+
+	menus, err := botconfig_parser.LoadLevels(*botConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintf(os.Stderr, "\nИсправьте ошибки, подробнее: validate --bot=%s\n", *botConfig)
+		return 1
+	}
+
+	if err := bot.Simulate(cnf, menus, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"connect-text-bot/internal/config"
+	"connect-text-bot/internal/transcript"
+
+	"github.com/google/uuid"
+)
+
+// exportTranscript - выгрузить переписку пользователя из архива
+func exportTranscript(args []string) int {
+	fs := flag.NewFlagSet("transcript", flag.ExitOnError)
+	var (
+		configFile = fs.String("config", "./config/config.yml", "Usage: -config=<config_file> (used for transcript.directory)")
+		dir        = fs.String("dir", "", "Usage: -dir=<dir> (overrides transcript.directory from config)")
+		user       = fs.String("user", "", "Usage: -user=<user_id>")
+		line       = fs.String("line", "", "Usage: -line=<line_id> (all lines by default)")
+		from       = fs.String("from", "", "Usage: -from=2006-01-02 or RFC3339")
+		to         = fs.String("to", "", "Usage: -to=2006-01-02 (inclusive) or RFC3339")
+		format     = fs.String("format", "text", "Usage: -format=text|jsonl")
+	)
+	_ = fs.Parse(args)
+
+	cnf := &config.Conf{}
+	if err := config.LoadConfig(*configFile, cnf); err != nil && *dir == "" {
+		fmt.Fprintln(os.Stderr, "Не удалось прочитать config.yml, используется каталог по умолчанию:", err)
+	}
+	if *dir != "" {
+		cnf.Transcript.Directory = *dir
+	}
+
+	var (
+		f   transcript.Filter
+		err error
+	)
+	if f.UserID, err = uuid.Parse(*user); err != nil {
+		fmt.Fprintln(os.Stderr, "Укажите id пользователя: --user=<user_id>")
+		return 1
+	}
+	if *line != "" {
+		if f.LineID, err = uuid.Parse(*line); err != nil {
+			fmt.Fprintln(os.Stderr, "Некорректный id линии:", err)
+			return 1
+		}
+	}
+	if f.From, err = transcript.ParseTime(*from, false); err != nil {
+		fmt.Fprintln(os.Stderr, "Некорректное начало периода:", err)
+		return 1
+	}
+	if f.To, err = transcript.ParseTime(*to, true); err != nil {
+		fmt.Fprintln(os.Stderr, "Некорректный конец периода:", err)
+		return 1
+	}
+
+	entries, err := transcript.Read(cnf.Transcript, f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch *format {
+	case "text":
+		for _, e := range entries {
+			fmt.Println(e)
+		}
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		for _, e := range entries {
+			_ = enc.Encode(e)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, "Неизвестный формат:", *format)
+		return 1
+	}
+	return 0
+}
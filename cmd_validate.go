@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"connect-text-bot/internal/botconfig_parser"
+	"connect-text-bot/internal/config"
+)
+
+// конфиг меню и каталог с его файлами, как их использует линия
+type validateTarget struct {
+	botConfig string
+	filesDir  string
+}
+
+// validate - проверить конфиг бота без запуска, код возврата 1 если есть ошибки
+func validate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var (
+		configFile = fs.String("config", "./config/config.yml", "Usage: -config=<config_file> (used for lines and files_dir)")
+		botConfig  = fs.String("bot", "./config/bot.yml", "Usage: -bot=<botConfig_file> (only this config, default for lines without bot_config)")
+		filesDir   = fs.String("files_dir", "", "Usage: -files_dir=<dir> (overrides files_dir from config)")
+		strict     = fs.Bool("strict", false, "Treat menu graph warnings as errors")
+	)
+	_ = fs.Parse(args)
+
+	onlyBot := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "bot" {
+			onlyBot = true
+		}
+	})
+
+	targets := validateTargets(*configFile, *botConfig, *filesDir, onlyBot)
+
+	var errCount, warnCount int
+	for _, t := range targets {
+		if len(targets) > 1 {
+			fmt.Printf("== %s (files_dir: %s)\n", t.botConfig, t.filesDir)
+		}
+
+		levels, errs := botconfig_parser.Validate(t.botConfig, t.filesDir)
+		for _, err := range errs {
+			fmt.Println(err)
+		}
+
+		// анализ графа меню имеет смысл только для корректного конфига
+		var warnings []error
+		if levels != nil && len(errs) == 0 {
+			warnings = botconfig_parser.WithPositions(t.botConfig, levels.Analyze())
+			for _, w := range warnings {
+				fmt.Println("Предупреждение:", w)
+			}
+		}
+
+		errCount += len(errs)
+		warnCount += len(warnings)
+	}
+
+	if errCount != 0 || (*strict && warnCount != 0) {
+		fmt.Printf("\nНайдено ошибок: %d, предупреждений: %d\n", errCount, warnCount)
+		return 1
+	}
+	if warnCount != 0 {
+		fmt.Printf("\nПредупреждений: %d\n", warnCount)
+	}
+
+	fmt.Println("OK")
+	return 0
+}
+
+// validateTargets - конфиги меню всех линий из config.yml с их files_dir, как их загружает бот.
+// Если указан только botConfig или линий нет - проверяется botConfig с общим files_dir
+func validateTargets(configFile, botConfig, filesDir string, onlyBot bool) (targets []validateTarget) {
+	cnf := &config.Conf{}
+	if err := config.LoadConfig(configFile, cnf); err != nil {
+		fmt.Fprintln(os.Stderr, "Не удалось прочитать config.yml, files_dir будет ./ :", err)
+	}
+	cnf.BotConfig = botConfig
+	cnf.SetLineDefaults()
+
+	dirOf := func(dir string) string {
+		if filesDir != "" {
+			return filesDir
+		}
+		if dir == "" {
+			return "./"
+		}
+		return dir
+	}
+
+	seen := make(map[validateTarget]bool)
+	for _, line := range cnf.Line {
+		path := filepath.Clean(line.BotConfig)
+		if onlyBot && path != filepath.Clean(botConfig) {
+			continue
+		}
+		t := validateTarget{botConfig: path, filesDir: dirOf(line.FilesDir)}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+
+	if len(targets) == 0 {
+		targets = append(targets, validateTarget{botConfig: botConfig, filesDir: dirOf(cnf.FilesDir)})
+	}
+	return
+}
@@ -0,0 +1,145 @@
+package botconfig_parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"connect-text-bot/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// меню, в которые бот переводит пользователя сам, без кнопок
+var implicitMenus = []string{database.START, database.FINAL, database.FAIL_QNA, database.WAIT_SEND, database.CREATE_TICKET}
+
+// IsTerminal - кнопка завершает работу бота с пользователем (закрытие, перевод на специалиста или линию)
+func (b *Button) IsTerminal() bool {
+	return b.CloseButton || b.RedirectButton ||
+		(b.AppointSpecButton != nil && *b.AppointSpecButton != uuid.Nil) ||
+		(b.AppointRandomSpecFromListButton != nil && len(*b.AppointRandomSpecFromListButton) != 0) ||
+		(b.RerouteButton != nil && *b.RerouteButton != uuid.Nil)
+}
+
+// Edges - куда можно перейти из меню по кнопкам
+func (l *Levels) Edges() map[string][]string {
+	edges := make(map[string][]string, len(l.Menu))
+	add := func(from, to string) {
+		if _, ok := l.Menu[to]; ok && !slices.Contains(edges[from], to) {
+			edges[from] = append(edges[from], to)
+		}
+	}
+
+	l.WalkButtons(func(menu string, b *Button) {
+		add(menu, b.Goto)
+		if b.TicketButton != nil {
+			add(menu, b.TicketButton.Goto)
+		}
+	})
+	return edges
+}
+
+// Analyze - анализ графа меню: недостижимые меню, бесконечные циклы из do_button и меню без выхода
+func (l *Levels) Analyze() (warnings []error) {
+	edges := l.Edges()
+
+	// недостижимые меню
+	reachable := make(map[string]bool)
+	queue := make([]string, 0, len(l.Menu))
+	for _, k := range implicitMenus {
+		if _, ok := l.Menu[k]; ok {
+			reachable[k] = true
+			queue = append(queue, k)
+		}
+	}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		for _, to := range edges[k] {
+			if !reachable[to] {
+				reachable[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+	for _, k := range l.menuKeys() {
+		if !reachable[k] {
+			warnings = append(warnings, &MenuError{Menu: k, Err: fmt.Errorf("меню недостижимо из %s: %s", database.START, k)})
+		}
+	}
+
+	// циклы из do_button, которые SendAnswer будет выполнять бесконечно
+	doNext := make(map[string]string)
+	for _, k := range l.menuKeys() {
+		if b := l.Menu[k].DoButton; b != nil && b.passesThrough() {
+			if _, ok := l.Menu[b.Goto]; ok {
+				doNext[k] = b.Goto
+			}
+		}
+	}
+	reported := make(map[string]bool)
+	for _, k := range l.menuKeys() {
+		path := []string{}
+		visited := make(map[string]int)
+		for cur, ok := k, true; ok && !reported[cur]; cur, ok = doNext[cur] {
+			if i, seen := visited[cur]; seen {
+				cycle := append(path[i:], cur)
+				for _, m := range cycle {
+					reported[m] = true
+				}
+				warnings = append(warnings, &MenuError{Menu: cur, Err: fmt.Errorf("бесконечный цикл из do_button: %s", strings.Join(cycle, " -> "))})
+				break
+			}
+			visited[cur] = len(path)
+			path = append(path, cur)
+		}
+	}
+
+	// меню без выхода: из них нельзя попасть в start/final_menu, вернуться назад или завершить диалог
+	exits := make(map[string]bool)
+	exits[database.START] = true
+	exits[database.FINAL] = true
+	for _, k := range l.menuKeys() {
+		v := l.Menu[k]
+		for _, b := range v.Buttons {
+			if b.Button.BackButton || b.Button.IsTerminal() {
+				exits[k] = true
+			}
+		}
+		if v.DoButton != nil && (v.DoButton.BackButton || v.DoButton.IsTerminal()) {
+			exits[k] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for k, to := range edges {
+			if exits[k] {
+				continue
+			}
+			if slices.ContainsFunc(to, func(m string) bool { return exits[m] }) {
+				exits[k] = true
+				changed = true
+			}
+		}
+	}
+	for _, k := range l.menuKeys() {
+		if !exits[k] {
+			warnings = append(warnings, &MenuError{Menu: k, Err: fmt.Errorf("из меню нет выхода (кнопок закрытия, перевода, назад или перехода в %s/%s): %s", database.START, database.FINAL, k)})
+		}
+	}
+
+	// клавиатура отправляется с последним сообщением меню, если все сообщения скрыты - кнопок пользователь не увидит
+	for _, k := range l.menuKeys() {
+		answers := l.Menu[k].Answer
+		if len(answers) > 0 && !slices.ContainsFunc(answers, func(a *Answer) bool { return a.ShowIf == "" }) {
+			warnings = append(warnings, &MenuError{Menu: k, Err: fmt.Errorf("у всех сообщений меню есть условие show_if, если ни одно не выполнится - клавиатура не будет отправлена: %s", k)})
+		}
+	}
+
+	return
+}
+
+// кнопка сразу выполняет переход по goto не дожидаясь пользователя
+func (b *Button) passesThrough() bool {
+	return b.Goto != "" && !b.IsTerminal() && !b.BackButton && b.SaveToVar == nil && b.TicketButton == nil
+}
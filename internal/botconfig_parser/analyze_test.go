@@ -0,0 +1,157 @@
+package botconfig_parser
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const analyzeConfig = `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Тупик"
+          goto: trap
+      - button:
+          id: 2
+          text: "Цикл"
+          goto: loop1
+      - button:
+          id: 3
+          text: "Закрыть"
+          close_button: true
+  trap:
+    answer:
+      - chat: "Отсюда не выйти"
+    buttons:
+      - button:
+          id: 1
+          text: "Дальше"
+          goto: trap2
+  trap2:
+    answer:
+      - chat: "И отсюда"
+    buttons:
+      - button:
+          id: 1
+          text: "Обратно"
+          goto: trap
+  loop1:
+    answer:
+      - chat: "1"
+    do_button:
+      goto: loop2
+  loop2:
+    answer:
+      - chat: "2"
+    do_button:
+      goto: loop1
+  orphan:
+    answer:
+      - chat: "Сюда никто не ведет"
+    buttons:
+      - button:
+          id: 1
+          text: "Назад"
+          back_button: true
+`
+
+// warningsByMenu - меню и текст предупреждений
+func warningsByMenu(t *testing.T, warnings []error) map[string][]string {
+	t.Helper()
+	got := make(map[string][]string)
+	for _, w := range warnings {
+		var menuErr *MenuError
+		if !errors.As(w, &menuErr) {
+			t.Fatalf("warning without menu: %v", w)
+		}
+		got[menuErr.Menu] = append(got[menuErr.Menu], w.Error())
+	}
+	return got
+}
+
+func hasWarning(got map[string][]string, menu, text string) bool {
+	return slices.ContainsFunc(got[menu], func(w string) bool { return strings.Contains(w, text) })
+}
+
+func TestAnalyze(t *testing.T) {
+	levels, errs := Validate(writeConfig(t, analyzeConfig), "./")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	got := warningsByMenu(t, levels.Analyze())
+
+	if !hasWarning(got, "orphan", "недостижимо") {
+		t.Errorf("orphan not reported as unreachable: %v", got)
+	}
+	if !hasWarning(got, "loop1", "бесконечный цикл из do_button: loop1 -> loop2 -> loop1") {
+		t.Errorf("do_button cycle not reported: %v", got)
+	}
+	if hasWarning(got, "loop2", "бесконечный цикл") {
+		t.Errorf("do_button cycle reported twice: %v", got)
+	}
+	for _, menu := range []string{"trap", "trap2"} {
+		if !hasWarning(got, menu, "нет выхода") {
+			t.Errorf("%s not reported as dead end: %v", menu, got)
+		}
+	}
+	for _, menu := range []string{"start", "final_menu", "orphan"} {
+		if hasWarning(got, menu, "нет выхода") {
+			t.Errorf("%s reported as dead end: %v", menu, got)
+		}
+	}
+	if hasWarning(got, "trap", "недостижимо") || hasWarning(got, "loop2", "недостижимо") {
+		t.Errorf("reachable menu reported as unreachable: %v", got)
+	}
+}
+
+func TestAnalyzeValidConfig(t *testing.T) {
+	levels, errs := Validate(writeConfig(t, `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Подменю"
+          menu:
+            id: sub
+            answer:
+              - chat: "Подменю"
+            buttons:
+              - button:
+                  id: 1
+                  text: "Назад"
+                  back_button: true
+      - button:
+          id: 2
+          text: "Закрыть"
+          close_button: true
+`), "./")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if warnings := levels.Analyze(); len(warnings) != 0 {
+		t.Fatalf("warnings for valid config: %v", warnings)
+	}
+}
+
+func TestEdges(t *testing.T) {
+	levels, errs := Validate(writeConfig(t, analyzeConfig), "./")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	edges := levels.Edges()
+
+	// close_button по умолчанию переходит в final_menu
+	if !slices.Equal(edges["start"], []string{"trap", "loop1", "final_menu"}) {
+		t.Errorf("edges of start = %v", edges["start"])
+	}
+	if !slices.Equal(edges["loop1"], []string{"loop2"}) {
+		t.Errorf("edges of loop1 = %v", edges["loop1"])
+	}
+}
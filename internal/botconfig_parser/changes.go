@@ -0,0 +1,70 @@
+package botconfig_parser
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Changes - что изменилось в меню при перечитывании конфига
+type Changes struct {
+	Path    string `json:"path"`
+	Version uint64 `json:"version"`
+	// хеш содержимого меню
+	Hash string `json:"hash,omitempty"`
+	// id добавленных, удаленных и измененных меню
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	// конфиг с ошибкой, продолжает работать версия Version
+	Error string `json:"error,omitempty"`
+}
+
+func diffMenus(path string, old, cur *Levels) Changes {
+	ch := Changes{Path: path, Version: cur.Version, Hash: cur.Hash}
+	for id, m := range cur.Menu {
+		prev, ok := old.Menu[id]
+		switch {
+		case !ok:
+			ch.Added = append(ch.Added, id)
+		case !reflect.DeepEqual(prev, m):
+			ch.Changed = append(ch.Changed, id)
+		}
+	}
+	for id := range old.Menu {
+		if _, ok := cur.Menu[id]; !ok {
+			ch.Removed = append(ch.Removed, id)
+		}
+	}
+	slices.Sort(ch.Added)
+	slices.Sort(ch.Removed)
+	slices.Sort(ch.Changed)
+	return ch
+}
+
+// Empty - меню не изменились
+func (ch Changes) Empty() bool {
+	return ch.Error == "" && len(ch.Added) == 0 && len(ch.Removed) == 0 && len(ch.Changed) == 0
+}
+
+func (ch Changes) String() string {
+	if ch.Error != "" {
+		return fmt.Sprintf("%s: ошибка, используется версия %d: %s", ch.Path, ch.Version, ch.Error)
+	}
+
+	parts := []string{fmt.Sprintf("%s: версия %d", ch.Path, ch.Version)}
+	if len(ch.Added) > 0 {
+		parts = append(parts, "добавлены меню "+strings.Join(ch.Added, ", "))
+	}
+	if len(ch.Removed) > 0 {
+		parts = append(parts, "удалены меню "+strings.Join(ch.Removed, ", "))
+	}
+	if len(ch.Changed) > 0 {
+		parts = append(parts, "изменены меню "+strings.Join(ch.Changed, ", "))
+	}
+	if len(parts) == 1 {
+		parts = append(parts, "меню не изменились")
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,60 @@
+package botconfig_parser
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReloadChanges(t *testing.T) {
+	path := writeConfig(t, menuConfig("Меню")+`  sub:
+    answer:
+      - chat: "Подменю"
+    buttons:
+      - button:
+          id: 1
+          text: "Назад"
+          back_button: true
+`)
+	s := InitMenuSet([]string{path})
+
+	if err := os.WriteFile(path, []byte(menuConfig("Новое меню")+`  added:
+    answer:
+      - chat: "Новое"
+    buttons:
+      - button:
+          id: 1
+          text: "Назад"
+          back_button: true
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	changes := s.ReloadAll()
+	if len(changes) != 1 {
+		t.Fatalf("changes = %v", changes)
+	}
+	ch := changes[0]
+	if !slices.Equal(ch.Added, []string{"added"}) || !slices.Equal(ch.Removed, []string{"sub"}) || !slices.Equal(ch.Changed, []string{"start"}) {
+		t.Errorf("changes = %+v", ch)
+	}
+	if ch.Version != s.Get(path).Load().Version || ch.Empty() {
+		t.Errorf("changes = %+v", ch)
+	}
+
+	// без изменений
+	changes = s.ReloadAll()
+	if !changes[0].Empty() || !strings.Contains(changes[0].String(), "меню не изменились") {
+		t.Errorf("changes of same config = %v", changes[0])
+	}
+
+	// ошибка сообщает версию, которая продолжает работать
+	version := s.Get(path).Load().Version
+	if err := os.WriteFile(path, []byte("menus: текст\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	changes = s.Update(path)
+	if len(changes) != 1 || changes[0].Error == "" || changes[0].Version != version {
+		t.Fatalf("changes of broken config = %+v", changes)
+	}
+}
@@ -0,0 +1,263 @@
+package botconfig_parser
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"connect-text-bot/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// виды узлов графа меню
+const (
+	NODE_MENU        = "menu"
+	NODE_ACTION      = "action"
+	NODE_SAVE_TO_VAR = "save_to_var"
+	NODE_TICKET      = "ticket"
+	// меню на которое ссылается goto, но которого нет в конфиге
+	NODE_MISSING = "missing"
+)
+
+// виды переходов графа меню
+const (
+	// переход по нажатию кнопки
+	EDGE_BUTTON = "button"
+	// переход без участия пользователя (do_button, после save_to_var, заявки, exec_button)
+	EDGE_DO_BUTTON = "do_button"
+)
+
+type (
+	GraphNode struct {
+		ID    string
+		Label string
+		Kind  string
+		// меню в которое бот переводит сам (start, final_menu и т.д.)
+		Implicit bool
+	}
+
+	GraphEdge struct {
+		From  string
+		To    string
+		Label string
+		Kind  string
+	}
+
+	// Graph - схема переходов между меню
+	Graph struct {
+		Nodes []GraphNode
+		Edges []GraphEdge
+
+		menus map[string]string
+	}
+)
+
+func (g *Graph) addNode(kind, label string) string {
+	id := fmt.Sprintf("n%d", len(g.Nodes))
+	g.Nodes = append(g.Nodes, GraphNode{
+		ID:       id,
+		Label:    label,
+		Kind:     kind,
+		Implicit: kind == NODE_MENU && slices.Contains(implicitMenus, label),
+	})
+	return id
+}
+
+func (g *Graph) addEdge(from, to, kind, label string) {
+	g.Edges = append(g.Edges, GraphEdge{From: from, To: to, Kind: kind, Label: label})
+}
+
+// узел меню, если меню нет в конфиге - то создаем узел отсутствующего меню
+func (g *Graph) menu(name string) string {
+	if id, ok := g.menus[name]; ok {
+		return id
+	}
+	id := g.addNode(NODE_MISSING, name)
+	g.menus[name] = id
+	return id
+}
+
+// Graph - построить схему переходов между меню по уже развернутым вложенным меню
+func (l *Levels) Graph() *Graph {
+	g := &Graph{menus: make(map[string]string, len(l.Menu))}
+	for _, k := range l.menuKeys() {
+		g.menus[k] = g.addNode(NODE_MENU, k)
+	}
+
+	for _, k := range l.menuKeys() {
+		v := l.Menu[k]
+		for _, b := range v.Buttons {
+			g.addButton(g.menus[k], &b.Button, EDGE_BUTTON, buttonLabel(&b.Button))
+		}
+		if v.DoButton != nil {
+			g.addButton(g.menus[k], v.DoButton, EDGE_DO_BUTTON, "do_button")
+		}
+	}
+	return g
+}
+
+// добавить переходы по кнопке, порядок проверок совпадает с порядком обработки кнопки ботом
+func (g *Graph) addButton(from string, b *Button, kind, label string) {
+	action := func(text string) {
+		g.addEdge(from, g.addNode(NODE_ACTION, text), kind, label)
+	}
+
+	switch {
+	case b.CloseButton:
+		action("close_button")
+	case b.RedirectButton:
+		action("redirect_button")
+	case b.AppointSpecButton != nil && *b.AppointSpecButton != uuid.Nil:
+		action("appoint_spec_button\n" + b.AppointSpecButton.String())
+	case b.AppointRandomSpecFromListButton != nil && len(*b.AppointRandomSpecFromListButton) != 0:
+		action(fmt.Sprintf("appoint_random_spec_from_list_button\n%d спец.", len(*b.AppointRandomSpecFromListButton)))
+	case b.RerouteButton != nil && *b.RerouteButton != uuid.Nil:
+		action("reroute_button\n" + b.RerouteButton.String())
+	case b.BackButton:
+		action("back_button")
+	case b.ExecButton != "":
+		exec := g.addNode(NODE_ACTION, "exec_button")
+		g.addEdge(from, exec, kind, label)
+		goTo := database.FINAL
+		if b.Goto != "" {
+			goTo = b.Goto
+		}
+		g.addEdge(exec, g.menu(goTo), EDGE_DO_BUTTON, "")
+	case b.SaveToVar != nil:
+		save := g.addNode(NODE_SAVE_TO_VAR, "save_to_var: "+b.SaveToVar.VarName)
+		g.addEdge(from, save, kind, label)
+		if b.SaveToVar.DoButton != nil {
+			g.addButton(save, b.SaveToVar.DoButton, EDGE_DO_BUTTON, "")
+		}
+	case b.TicketButton != nil:
+		ticket := g.addNode(NODE_TICKET, "ticket_button")
+		g.addEdge(from, ticket, kind, label)
+		if b.TicketButton.Goto != "" {
+			g.addEdge(ticket, g.menu(b.TicketButton.Goto), EDGE_DO_BUTTON, "")
+		}
+	case b.Goto == database.CREATE_TICKET_PREV_STAGE:
+		// служебный переход к предыдущему этапу заявки
+		action(database.CREATE_TICKET_PREV_STAGE)
+	case b.Goto != "":
+		g.addEdge(from, g.menu(b.Goto), kind, label)
+	}
+}
+
+func buttonLabel(b *Button) string {
+	if b.ButtonID != "" {
+		return b.ButtonID + ". " + b.ButtonText
+	}
+	return b.ButtonText
+}
+
+// DOT - схема в формате Graphviz
+func (g *Graph) DOT() string {
+	quote := func(s string) string {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph bot {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	sb.WriteString("\tnode [shape=box];\n")
+
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + quote(n.Label)}
+		switch n.Kind {
+		case NODE_ACTION:
+			attrs = append(attrs, "shape=octagon")
+		case NODE_SAVE_TO_VAR:
+			attrs = append(attrs, "shape=parallelogram")
+		case NODE_TICKET:
+			attrs = append(attrs, "shape=folder")
+		case NODE_MISSING:
+			attrs = append(attrs, "style=dashed", "color=red")
+		}
+		if n.Implicit {
+			attrs = append(attrs, "peripheries=2")
+		}
+		fmt.Fprintf(&sb, "\t%s [%s];\n", n.ID, strings.Join(attrs, ", "))
+	}
+
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Label != "" {
+			attrs = append(attrs, "label="+quote(e.Label))
+		}
+		if e.Kind == EDGE_DO_BUTTON {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) == 0 {
+			fmt.Fprintf(&sb, "\t%s -> %s;\n", e.From, e.To)
+		} else {
+			fmt.Fprintf(&sb, "\t%s -> %s [%s];\n", e.From, e.To, strings.Join(attrs, ", "))
+		}
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid - схема в формате Mermaid flowchart
+func (g *Graph) Mermaid() string {
+	// спецсимволы заменяются кодами Mermaid, # первым чтобы не задеть сами коды
+	escape := strings.NewReplacer(
+		"#", "#35;",
+		`"`, "#quot;",
+		"|", "#124;",
+		"<", "#lt;",
+		">", "#gt;",
+	)
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(escape.Replace(s), "\n", "<br/>") + `"`
+	}
+
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+
+	var implicit, missing []string
+	for _, n := range g.Nodes {
+		label := quote(n.Label)
+		switch n.Kind {
+		case NODE_ACTION:
+			fmt.Fprintf(&sb, "\t%s{{%s}}\n", n.ID, label)
+		case NODE_SAVE_TO_VAR:
+			fmt.Fprintf(&sb, "\t%s[/%s/]\n", n.ID, label)
+		case NODE_TICKET:
+			fmt.Fprintf(&sb, "\t%s[[%s]]\n", n.ID, label)
+		default:
+			fmt.Fprintf(&sb, "\t%s[%s]\n", n.ID, label)
+		}
+		if n.Implicit {
+			implicit = append(implicit, n.ID)
+		}
+		if n.Kind == NODE_MISSING {
+			missing = append(missing, n.ID)
+		}
+	}
+
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind == EDGE_DO_BUTTON {
+			arrow = "-.->"
+		}
+		if e.Label != "" {
+			fmt.Fprintf(&sb, "\t%s %s|%s| %s\n", e.From, arrow, quote(e.Label), e.To)
+		} else {
+			fmt.Fprintf(&sb, "\t%s %s %s\n", e.From, arrow, e.To)
+		}
+	}
+
+	if len(implicit) != 0 {
+		sb.WriteString("\tclassDef implicit stroke-width:3px\n")
+		fmt.Fprintf(&sb, "\tclass %s implicit\n", strings.Join(implicit, ","))
+	}
+	if len(missing) != 0 {
+		sb.WriteString("\tclassDef missing stroke:red,stroke-dasharray:5 5\n")
+		fmt.Fprintf(&sb, "\tclass %s missing\n", strings.Join(missing, ","))
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package botconfig_parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const flowConfig = `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Договор"
+          save_to_var:
+            var_name: contract
+            send_text: "Номер?"
+            do_button:
+              goto: start
+      - button:
+          id: 2
+          text: "Потерянное"
+          goto: lost
+      - button:
+          id: 3
+          text: "Закрыть"
+          close_button: true
+`
+
+func TestGraph(t *testing.T) {
+	levels, err := LoadLevels(writeConfig(t, flowConfig))
+	if levels == nil {
+		t.Fatal(err)
+	}
+	g := levels.Graph()
+
+	kinds := make(map[string]string)
+	for _, n := range g.Nodes {
+		kinds[n.Label] = n.Kind
+	}
+	if kinds["start"] != NODE_MENU || kinds["lost"] != NODE_MISSING {
+		t.Errorf("nodes = %+v", g.Nodes)
+	}
+
+	dot := g.DOT()
+	for _, want := range []string{"digraph bot {", "shape=parallelogram", `label="lost", style=dashed, color=red`, `[label="1. Договор"]`, "style=dashed];"} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT has no %s:\n%s", want, dot)
+		}
+	}
+
+	mermaid := g.Mermaid()
+	for _, want := range []string{"flowchart LR", `[/"save_to_var`, `-->|"2. Потерянное"|`, "class ", "missing"} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid has no %s:\n%s", want, mermaid)
+		}
+	}
+}
+
+const graphConfig = `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Да | <нет> #1 \"ок\""
+          goto: final_menu
+      - button:
+          id: 2
+          text: "Закрыть"
+          close_button: true
+`
+
+func TestMermaidEscapesLabels(t *testing.T) {
+	levels, err := LoadLevels(writeConfig(t, graphConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := levels.Graph().Mermaid()
+	want := `|"1. Да #124; #lt;нет#gt; #35;1 #quot;ок#quot;"|`
+	if !strings.Contains(out, want) {
+		t.Fatalf("mermaid output does not contain %s:\n%s", want, out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "Да") && strings.Count(line, "|") != 2 {
+			t.Fatalf("edge label breaks mermaid syntax: %s", line)
+		}
+	}
+}
+
+func TestLoadLevelsReturnsMenuWithCheckErrors(t *testing.T) {
+	levels, err := LoadLevels(writeConfig(t, `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Никуда"
+          goto: nowhere
+`))
+	if err == nil || levels == nil {
+		t.Fatalf("LoadLevels = %v, %v; want menu and error", levels, err)
+	}
+	if !strings.Contains(levels.Graph().DOT(), "nowhere") {
+		t.Fatal("graph of invalid config has no missing menu")
+	}
+
+	levels, err = LoadLevels(writeConfig(t, "menus: [\n"))
+	if err == nil || levels != nil {
+		t.Fatalf("LoadLevels of broken yaml = %v, %v; want nil and error", levels, err)
+	}
+}
@@ -27,6 +27,11 @@ type Levels struct {
 
 	// сообщения об ошибках
 	ErrorMessages ErrorMessages `yaml:"error_messages"`
+
+	// версия меню, растет с каждой загрузкой конфига
+	Version uint64 `yaml:"-" json:"-"`
+	// хеш содержимого меню, одинаковый для одного конфига после перезапуска и на всех репликах
+	Hash string `yaml:"-" json:"-"`
 }
 
 type Menu struct {
@@ -47,6 +52,8 @@ type ErrorMessages struct {
 	ButtonProcessing string `yaml:"button_processing"`
 	// Ошибка: Не удалось отправить файл
 	FailedSendFile string `yaml:"failed_send_file"`
+	// Меню бота обновилось, начнем сначала
+	MenuUpdated string `yaml:"menu_updated"`
 
 	AppointSpecButton struct {
 		// Выбранный специалист недоступен
@@ -84,6 +91,8 @@ type Answer struct {
 	File string `yaml:"file,omitempty"`
 	// сопроводительный текст к файлу
 	FileText string `yaml:"file_text,omitempty"`
+	// условие, при котором сообщение отправляется
+	ShowIf string `yaml:"show_if,omitempty"`
 }
 
 type Buttons struct {
@@ -103,6 +112,8 @@ type Button struct {
 	ButtonID string `yaml:"id"`
 	// текст кнопки
 	ButtonText string `yaml:"text"`
+	// условие, при котором кнопка показывается
+	ShowIf string `yaml:"show_if,omitempty"`
 	// сообщение
 	Chat []*Answer `yaml:"chat,omitempty"`
 	// закрыть обращение
@@ -199,7 +210,9 @@ func (b Button) View() (btnStr string) {
 
 func (b SaveToVar) View() (btnStr string) {
 	btnStr += fmt.Sprintf("\nVarName: %s", b.VarName)
-	btnStr += fmt.Sprintf("\nSendText: %s", *b.SendText)
+	if b.SendText != nil {
+		btnStr += fmt.Sprintf("\nSendText: %s", *b.SendText)
+	}
 	btnStr += fmt.Sprintf("\nlen(OfferOptions): %d", len(b.OfferOptions))
 
 	if b.DoButton != nil {
@@ -0,0 +1,162 @@
+package botconfig_parser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"connect-text-bot/internal/logger"
+)
+
+// MenuSet - меню бота по пути к конфигу, линии с одним конфигом используют общее меню
+type MenuSet struct {
+	mu     sync.RWMutex
+	levels map[string]*Versioned
+}
+
+// InitMenuSet - загрузить меню из всех конфигов, при ошибке в конфиге приложение завершается
+func InitMenuSet(paths []string) *MenuSet {
+	s := &MenuSet{levels: make(map[string]*Versioned, len(paths))}
+	for _, p := range paths {
+		logger.Info("Load bot config", p)
+		s.levels[p] = newVersioned(p, InitLevels(p))
+	}
+	return s
+}
+
+// Get - меню из конфига path, nil если конфиг не загружен
+func (s *MenuSet) Get(path string) *Versioned {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.levels[path]
+}
+
+// Load - меню из конфига path, незагруженный конфиг читается.
+// В отличие от InitMenuSet ошибка в конфиге не завершает приложение
+func (s *MenuSet) Load(path string) (*Versioned, error) {
+	if v := s.Get(path); v != nil {
+		return v, nil
+	}
+
+	logger.Info("Load bot config", path)
+	l, err := LoadLevels(path)
+	if err != nil {
+		return nil, err
+	}
+	l.logWarnings()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if loaded, ok := s.levels[path]; ok {
+		return loaded, nil
+	}
+	v := newVersioned(path, l)
+	s.levels[path] = v
+	return v, nil
+}
+
+// Retain - оставить только конфиги paths, остальные больше не перечитываются
+func (s *MenuSet) Retain(paths []string) {
+	keep := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		keep[p] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for p := range s.levels {
+		if !keep[p] {
+			delete(s.levels, p)
+		}
+	}
+}
+
+// Update - перечитать конфиги, в каталогах которых изменились файлы changed.
+// Каждый конфиг перечитывается один раз, сколько бы его файлов ни изменилось
+func (s *MenuSet) Update(changed ...string) []Changes {
+	var changedAbs []string
+	for _, c := range changed {
+		if abs, err := filepath.Abs(c); err == nil {
+			changedAbs = append(changedAbs, abs)
+		}
+	}
+
+	return s.reload(func(dir string) bool {
+		return slices.ContainsFunc(changedAbs, func(c string) bool {
+			return strings.HasPrefix(c, dir+string(filepath.Separator))
+		})
+	})
+}
+
+// ReloadAll - перечитать все конфиги
+func (s *MenuSet) ReloadAll() []Changes {
+	return s.reload(func(string) bool { return true })
+}
+
+// reload - перечитать конфиги, для каталога которых match вернул true
+func (s *MenuSet) reload(match func(dir string) bool) (changes []Changes) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for p, v := range s.levels {
+		dir, err := filepath.Abs(filepath.Dir(p))
+		if err != nil || !match(dir) {
+			continue
+		}
+		ch, err := v.Reload()
+		if err != nil {
+			logger.Warning("Не корректный конфиг бота, используется предыдущая версия!", p, err)
+		}
+		changes = append(changes, ch)
+	}
+
+	slices.SortFunc(changes, func(a, b Changes) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return changes
+}
+
+// Dirs - каталоги с конфигами и все вложенные, за изменениями в которых нужно следить
+func (s *MenuSet) Dirs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for p := range s.levels {
+		err := filepath.Walk(filepath.Dir(p), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && !seen[path] {
+				seen[path] = true
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// Loaded - проверка что меню всех линий загружены
+func (s *MenuSet) Loaded(ctx context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.levels) == 0 {
+		return fmt.Errorf("меню бота не загружено")
+	}
+	for p, l := range s.levels {
+		if err := l.Loaded(ctx); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package botconfig_parser
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func menuConfig(text string) string {
+	return `menus:
+  start:
+    answer:
+      - chat: "` + text + `"
+    buttons:
+      - button:
+          id: 1
+          text: "Закрыть"
+          close_button: true
+`
+}
+
+func TestMenuSet(t *testing.T) {
+	dir := t.TempDir()
+	general := filepath.Join(dir, "bot.yml")
+	sales := filepath.Join(dir, "sales", "bot.yml")
+	if err := os.Mkdir(filepath.Dir(sales), 0777); err != nil {
+		t.Fatal(err)
+	}
+	for path, text := range map[string]string{general: "Общее", sales: "Продажи"} {
+		if err := os.WriteFile(path, []byte(menuConfig(text)), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := InitMenuSet([]string{general, sales})
+	if err := s.Loaded(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	shared := s.Get(general)
+
+	dirs, err := s.Dirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 {
+		t.Errorf("Dirs() = %v", dirs)
+	}
+
+	// изменение файла перечитывает конфиги, в каталоге которых он лежит
+	if err := os.WriteFile(sales, []byte(menuConfig("Продажи 2")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s.Update(sales)
+	if got := s.Get(sales).Load().Menu["start"].Answer[0].Chat; got != "Продажи 2" {
+		t.Errorf("sales menu = %q after update", got)
+	}
+
+	// линии продолжают видеть то же меню с новой версией
+	if err := os.WriteFile(general, []byte(menuConfig("Общее 2")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s.Update(general)
+	if s.Get(general) != shared {
+		t.Fatal("menu replaced on update")
+	}
+	if got := shared.Load().Menu["start"].Answer[0].Chat; got != "Общее 2" {
+		t.Errorf("general menu = %q after update", got)
+	}
+
+	// ошибка в конфиге оставляет прежнее меню
+	if err := os.WriteFile(general, []byte("menus: текст\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	s.Update(general)
+	if got := shared.Load().Menu["start"].Answer[0].Chat; got != "Общее 2" {
+		t.Errorf("general menu = %q after broken update", got)
+	}
+}
+
+func TestMenuSetLoadRetain(t *testing.T) {
+	dir := t.TempDir()
+	general := filepath.Join(dir, "bot.yml")
+	added := filepath.Join(dir, "added.yml")
+	broken := filepath.Join(dir, "broken.yml")
+	for path, content := range map[string]string{general: menuConfig("Общее"), added: menuConfig("Новая линия"), broken: "menus: текст\n"} {
+		if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := InitMenuSet([]string{general})
+	l, err := s.Load(general)
+	if err != nil || l != s.Get(general) {
+		t.Fatalf("Load(loaded) = %p, %v, want %p", l, err, s.Get(general))
+	}
+
+	// конфиг новой линии загружается при первом обращении
+	l, err = s.Load(added)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Get(added) != l {
+		t.Fatal("loaded menu not stored")
+	}
+
+	// ошибка в конфиге не завершает приложение и не сохраняется
+	if _, err := s.Load(broken); err == nil {
+		t.Fatal("broken config loaded")
+	}
+	if s.Get(broken) != nil {
+		t.Fatal("broken config stored")
+	}
+
+	s.Retain([]string{added})
+	if s.Get(general) != nil || s.Get(added) == nil {
+		t.Fatal("Retain kept wrong configs")
+	}
+}
+
+func TestMenuSetNotLoaded(t *testing.T) {
+	if err := (&MenuSet{}).Loaded(context.Background()); err == nil {
+		t.Fatal("empty menu set loaded")
+	}
+}
@@ -2,60 +2,55 @@ package botconfig_parser
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
 	"slices"
+	"strconv"
 	"strings"
-	"sync"
 
 	"connect-text-bot/internal/connect/requests"
 	"connect-text-bot/internal/database"
 	"connect-text-bot/internal/logger"
 
-	"github.com/gin-gonic/gin"
 	"github.com/goccy/go-yaml"
 	"github.com/google/uuid"
 )
 
-var lock = &sync.RWMutex{}
-var levels *Levels
-
+// InitLevels - загрузить меню бота, при ошибке в конфиге приложение завершается
 func InitLevels(path string) *Levels {
-	if levels == nil {
-		lock.Lock()
-		defer lock.Unlock()
-		if levels == nil {
-			var err error
-			levels, err = loadMenus(path)
-			if err != nil {
-				logger.Crit(err)
-			}
-			err = levels.checkMenus()
-			if err != nil {
-				logger.Crit(err)
-			}
-		} else {
-			logger.Warning("Levels already created")
-		}
-	} else {
-		logger.Warning("Levels already created")
+	levels, err := loadMenus(path)
+	if err != nil {
+		logger.Crit(err)
 	}
+	levels.logWarnings()
 	return levels
 }
 
-func (_ *Levels) UpdateLevels(path string) error {
-	newLevel, err := loadMenus(path)
-	if err != nil {
-		return err
+// Loaded - проверка что меню бота загружено
+func (l *Levels) Loaded(_ context.Context) error {
+	if l == nil || l.Menu == nil {
+		return errors.New("меню бота не загружено")
 	}
-	if err := newLevel.checkMenus(); err != nil {
-		return err
+	if _, ok := l.Menu[database.START]; !ok {
+		return fmt.Errorf("отсутствует меню %s", database.START)
 	}
-	*levels = *newLevel
 	return nil
 }
 
+// LoadLevels - загрузить меню без запуска бота.
+// Если файл не прочитан или не разобран, возвращает nil и ошибку. Если меню разобрано, но не прошло
+// проверку, возвращает и меню, и ошибку (объединенные *MenuError): по такому меню можно построить
+// схему (graph) и показать все ошибки (validate), но работать с ним бот не должен (simulate, test)
+func LoadLevels(pathCnf string) (*Levels, error) {
+	if _, err := os.Stat(pathCnf); err != nil {
+		return nil, err
+	}
+	return loadMenus(pathCnf)
+}
+
 func loadMenus(pathCnf string) (*Levels, error) {
 	input, _ := os.ReadFile(pathCnf)
 	dec := yaml.NewDecoder(bytes.NewBuffer(input), yaml.ReferenceDirs(path.Dir(pathCnf)), yaml.RecursiveDir(true))
@@ -69,7 +64,7 @@ func loadMenus(pathCnf string) (*Levels, error) {
 		if v.Buttons != nil {
 			err := nestedToFlat(menu, v.Buttons, k, 1)
 			if err != nil {
-				return nil, err
+				return nil, &MenuError{Menu: k, Err: err}
 			}
 		}
 
@@ -77,13 +72,17 @@ func loadMenus(pathCnf string) (*Levels, error) {
 			v.DoButton.ButtonText = "<do_button>"
 			err := nestedToFlat(menu, []*Buttons{{Button: *v.DoButton}}, k, 1)
 			if err != nil {
-				return nil, err
+				return nil, &MenuError{Menu: k, Err: err}
 			}
 		}
 	}
 
 	// проверяем все меню
-	return menu, menu.checkMenus()
+	if err := menu.checkMenus(); err != nil {
+		return menu, err
+	}
+	menu.Hash = menu.contentHash()
+	return menu, nil
 }
 
 func defaultFinalMenu() *Menu {
@@ -209,6 +208,42 @@ func nestedToFlat(main *Levels, buttons []*Buttons, k string, depthLevel int) er
 	return nil
 }
 
+// вывести в лог результаты анализа графа меню
+func (l *Levels) logWarnings() {
+	for _, w := range l.Analyze() {
+		logger.Warning(w)
+	}
+}
+
+// MenuError - ошибка в настройке конкретного меню
+type MenuError struct {
+	Menu string
+	// путь к полю с ошибкой внутри меню, например buttons.0.button.goto, пустой - ошибка всего меню
+	Path []string
+	Err  error
+}
+
+func (e *MenuError) Error() string { return e.Err.Error() }
+func (e *MenuError) Unwrap() error { return e.Err }
+
+// fieldError - ошибка в поле кнопки, path - путь к полю от кнопки
+type fieldError struct {
+	path []string
+	err  error
+}
+
+func (e *fieldError) Error() string { return e.err.Error() }
+func (e *fieldError) Unwrap() error { return e.err }
+
+func atField(err error, path ...string) error {
+	return &fieldError{path: path, err: err}
+}
+
+// путь к кнопке i списка buttons
+func buttonPath(i int) []string {
+	return []string{"buttons", strconv.Itoa(i), "button"}
+}
+
 func (l *Levels) checkMenus() error {
 	if _, ok := l.Menu[database.START]; !ok {
 		return fmt.Errorf("отсутствует меню %s", database.START)
@@ -233,56 +268,68 @@ func (l *Levels) checkMenus() error {
 	// настраиваем текста ошибок по умолчанию которые не настроены
 	l.setDefaultErrorMessages()
 
-	// проверка меню и подуровней
-	for k, v := range l.Menu {
-		if len(v.Buttons) == 0 && v.DoButton == nil {
-			return fmt.Errorf("отсутствуют кнопки: %s {%s}", k, v.View())
-		}
-
-		if v.Buttons != nil && v.DoButton != nil {
-			return fmt.Errorf("нельзя использовать одновременно buttons и do_button: %s {%s}", k, v.View())
-		}
+	// проверка меню и подуровней, собираем все ошибки а не только первую
+	keys := make([]string, 0, len(l.Menu))
+	for k := range l.Menu {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
 
-		if v.Buttons != nil {
-			err := l.checkMenuLevels(v.Buttons, k, v, 1)
-			if err != nil {
-				return err
+	var errs []error
+	for _, k := range keys {
+		for _, err := range l.checkMenu(k, l.Menu[k]) {
+			// ошибки кнопок уже содержат путь к полю
+			if _, ok := err.(*MenuError); !ok {
+				err = &MenuError{Menu: k, Err: err}
 			}
+			errs = append(errs, err)
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		if v.DoButton != nil {
-			err := l.checkMenuLevels([]*Buttons{{Button: *v.DoButton}}, k, v, 1)
-			if err != nil {
-				return err
-			}
-		}
+// проверка одного меню
+func (l *Levels) checkMenu(k string, v *Menu) []error {
+	if len(v.Buttons) == 0 && v.DoButton == nil {
+		return []error{fmt.Errorf("отсутствуют кнопки: %s {%s}", k, v.View())}
 	}
-	return nil
+
+	if v.Buttons != nil && v.DoButton != nil {
+		return []error{fmt.Errorf("нельзя использовать одновременно buttons и do_button: %s {%s}", k, v.View())}
+	}
+
+	if len(v.Answer) == 0 || !IsAnyAnswer(v.Answer) {
+		return []error{fmt.Errorf("отсутствует сообщение сопровождающее меню: %s", k)}
+	}
+
+	if v.Buttons != nil {
+		return l.checkMenuLevels(v.Buttons, buttonPath, k, v, 1)
+	}
+	return l.checkMenuLevels([]*Buttons{{Button: *v.DoButton}}, func(int) []string { return []string{"do_button"} }, k, v, 1)
 }
 
-// рекурсивная проверка меню и подуровней
-func (l *Levels) checkMenuLevels(buttons []*Buttons, k string, v *Menu, depthLevel int) error {
-	for _, b := range buttons {
+// рекурсивная проверка кнопок меню, at - путь к кнопке с индексом i внутри меню
+// вложенные меню не проверяем т.к. после nestedToFlat они находятся в Levels.Menu и проверяются отдельно
+func (l *Levels) checkMenuLevels(buttons []*Buttons, at func(i int) []string, k string, v *Menu, depthLevel int) (errs []error) {
+	for i, b := range buttons {
+		path := at(i)
+
 		err := l.checkButton(b, k, v, depthLevel)
 		if err != nil {
-			return err
-		}
-
-		if b.Button.NestedMenu != nil && b.Button.NestedMenu.Buttons != nil {
-			err := l.checkMenuLevels(b.Button.NestedMenu.Buttons, k, v, depthLevel+1)
-			if err != nil {
-				return err
+			errPath := path
+			var fieldErr *fieldError
+			if errors.As(err, &fieldErr) {
+				errPath = append(slices.Clone(path), fieldErr.path...)
 			}
+			errs = append(errs, &MenuError{Menu: k, Path: errPath, Err: err})
 		}
 
 		if b.Button.SaveToVar != nil && b.Button.SaveToVar.DoButton != nil {
-			err := l.checkMenuLevels([]*Buttons{{Button: *b.Button.SaveToVar.DoButton}}, k, v, depthLevel+1)
-			if err != nil {
-				return err
-			}
+			doPath := append(slices.Clone(path), "save_to_var", "do_button")
+			errs = append(errs, l.checkMenuLevels([]*Buttons{{Button: *b.Button.SaveToVar.DoButton}}, func(int) []string { return doPath }, k, v, depthLevel+1)...)
 		}
 	}
-	return nil
+	return
 }
 
 // проверка кнопки на валидность
@@ -297,16 +344,16 @@ func (l *Levels) checkButton(b *Buttons, k string, v *Menu, depthLevel int) erro
 		sBtnView := b.Button.SaveToVar.View()
 
 		if b.Button.SaveToVar.VarName == "" {
-			return fmt.Errorf("SaveToVar: отсутствует var_name (имя переменной для сохранения данных): %s {%s} lvl:%d", k, sBtnView, depthLevel)
+			return atField(fmt.Errorf("SaveToVar: отсутствует var_name (имя переменной для сохранения данных): %s {%s} lvl:%d", k, sBtnView, depthLevel), "save_to_var", "var_name")
 		}
 		if b.Button.SaveToVar.VarName == database.VAR_FOR_SAVE {
-			return fmt.Errorf("SaveToVar: используется зарезервированное имя переменной %s {%s} lvl:%d", k, sBtnView, depthLevel)
+			return atField(fmt.Errorf("SaveToVar: используется зарезервированное имя переменной %s {%s} lvl:%d", k, sBtnView, depthLevel), "save_to_var", "var_name")
 		}
 		if b.Button.SaveToVar.DoButton == nil {
-			return fmt.Errorf("SaveToVar: отсутствует do_button (действие которое выполнится после ответа пользователя): %s {%s} lvl:%d", k, sBtnView, depthLevel)
+			return atField(fmt.Errorf("SaveToVar: отсутствует do_button (действие которое выполнится после ответа пользователя): %s {%s} lvl:%d", k, sBtnView, depthLevel), "save_to_var")
 		}
 		if b.Button.SaveToVar.DoButton.BackButton {
-			return fmt.Errorf("SaveToVar: в do_button нельзя использовать back_button: %s {%s} lvl:%d", k, sBtnView, depthLevel)
+			return atField(fmt.Errorf("SaveToVar: в do_button нельзя использовать back_button: %s {%s} lvl:%d", k, sBtnView, depthLevel), "save_to_var", "do_button", "back_button")
 		}
 		modifycatorCount++
 	}
@@ -319,25 +366,25 @@ func (l *Levels) checkButton(b *Buttons, k string, v *Menu, depthLevel int) erro
 		tBtn := b.Button.TicketButton
 		tBtnView := b.Button.TicketButton.View()
 		if tBtn.ChannelID == uuid.Nil {
-			return fmt.Errorf("TicketButton: отсутствует канал связи (channel_id): %s {%s} lvl:%d", k, tBtnView, depthLevel)
+			return atField(fmt.Errorf("TicketButton: отсутствует канал связи (channel_id): %s {%s} lvl:%d", k, tBtnView, depthLevel), "ticket_button", "channel_id")
 		}
 		if tBtn.TicketInfo == "" {
-			return fmt.Errorf("TicketButton: отсутствует шаблон текста, где выводятся заполненные данные заявки (ticket_info): %s {%s} lvl:%d", k, tBtnView, depthLevel)
+			return atField(fmt.Errorf("TicketButton: отсутствует шаблон текста, где выводятся заполненные данные заявки (ticket_info): %s {%s} lvl:%d", k, tBtnView, depthLevel), "ticket_button", "ticket_info")
 		}
 		if tBtn.Data == nil {
-			return fmt.Errorf("TicketButton: отсутствуют данные заполняемой заявки (data): %s {%s} lvl:%d", k, tBtnView, depthLevel)
+			return atField(fmt.Errorf("TicketButton: отсутствуют данные заполняемой заявки (data): %s {%s} lvl:%d", k, tBtnView, depthLevel), "ticket_button", "data")
 		}
 
 		validateField := func(field *PartTicket, fieldName string) error {
 			if field == nil {
-				return fmt.Errorf("TicketButton: отсутствует поле (%s): %s {%s} lvl:%d", fieldName, k, tBtnView, depthLevel)
+				return atField(fmt.Errorf("TicketButton: отсутствует поле (%s): %s {%s} lvl:%d", fieldName, k, tBtnView, depthLevel), "ticket_button", "data")
 			}
 			if field.Text == "" && field.DefaultValue == nil {
-				return fmt.Errorf("TicketButton: поле (%s) должно содержать text или value: %s {%s} lvl:%d", fieldName, k, tBtnView, depthLevel)
+				return atField(fmt.Errorf("TicketButton: поле (%s) должно содержать text или value: %s {%s} lvl:%d", fieldName, k, tBtnView, depthLevel), "ticket_button", "data", fieldName)
 			}
 			if field.DefaultValue != nil && !slices.Contains([]string{"theme", "description"}, fieldName) {
 				if _, err := uuid.Parse(*field.DefaultValue); err != nil {
-					return fmt.Errorf("TicketButton: value не id (%s): %s {%s} lvl:%d", fieldName, k, tBtnView, depthLevel)
+					return atField(fmt.Errorf("TicketButton: value не id (%s): %s {%s} lvl:%d", fieldName, k, tBtnView, depthLevel), "ticket_button", "data", fieldName, "value")
 				}
 			}
 			return nil
@@ -409,13 +456,10 @@ func (l *Levels) checkButton(b *Buttons, k string, v *Menu, depthLevel int) erro
 		return fmt.Errorf("кнопка может иметь только один модификатор: %s {%s} lvl:%d", k, b.Button.View(), depthLevel)
 	}
 	if b.Button.Goto != "" && b.Button.BackButton {
-		return fmt.Errorf("back_button не может иметь goto: %s {%s} lvl:%d", k, b.Button.View(), depthLevel)
+		return atField(fmt.Errorf("back_button не может иметь goto: %s {%s} lvl:%d", k, b.Button.View(), depthLevel), "goto")
 	}
 	if _, ok := l.Menu[b.Button.Goto]; b.Button.Goto != "" && !ok && b.Button.Goto != database.CREATE_TICKET_PREV_STAGE {
-		return fmt.Errorf("кнопка ведет на несуществующий уровень: %s {%s} lvl:%d", k, b.Button.View(), depthLevel)
-	}
-	if len(v.Answer) == 0 || !IsAnyAnswer(v.Answer) {
-		return fmt.Errorf("отсутствует сообщение сопровождающее меню: %s lvl:%d", k, depthLevel)
+		return atField(fmt.Errorf("кнопка ведет на несуществующий уровень: %s {%s} lvl:%d", k, b.Button.View(), depthLevel), "goto")
 	}
 	if b.Button.ButtonText == "" {
 		return fmt.Errorf("текст у кнопки не может быть пустой %s {%s} lvl:%d", k, b.Button.View(), depthLevel)
@@ -432,6 +476,7 @@ func (l *Levels) setDefaultErrorMessages() {
 		{&l.ErrorMessages.CommandUnknown, "Команда неизвестна. Попробуйте еще раз"},
 		{&l.ErrorMessages.ButtonProcessing, "Во время обработки вашего запроса произошла ошибка"},
 		{&l.ErrorMessages.FailedSendFile, "Ошибка: Не удалось отправить файл"},
+		{&l.ErrorMessages.MenuUpdated, "Меню бота обновилось, начнем сначала"},
 		{&l.ErrorMessages.AppointSpecButton.SelectedSpecNotAvailable, "Выбранный специалист недоступен"},
 		{&l.ErrorMessages.AppointRandomSpecFromListButton.SpecsNotAvailable, "Специалисты данной области недоступны"},
 		{&l.ErrorMessages.RerouteButton.SelectedLineNotAvailable, "Выбранная линия недоступна"},
@@ -480,10 +525,13 @@ func Quotes(s string) string {
 	return answer.String()
 }
 
-// GenKeyboard - создать клавиатуру
-func (l *Levels) GenKeyboard(menu string) *[][]requests.KeyboardKey {
+// GenKeyboard - создать клавиатуру из кнопок, которые видны пользователю с данными data
+func (l *Levels) GenKeyboard(menu string, data any) *[][]requests.KeyboardKey {
 	answer := &[][]requests.KeyboardKey{}
 	for _, v := range l.Menu[menu].Buttons {
+		if !v.Button.Visible(data) {
+			continue
+		}
 		*answer = append(*answer, []requests.KeyboardKey{{ID: v.Button.ButtonID, Text: Quotes(v.Button.ButtonText)}})
 	}
 	if len(*answer) == 0 {
@@ -492,18 +540,15 @@ func (l *Levels) GenKeyboard(menu string) *[][]requests.KeyboardKey {
 	return answer
 }
 
-func (l *Levels) GetButton(menu, text string) *Button {
+// GetButton - найти нажатую кнопку, скрытые от пользователя кнопки нажать нельзя
+func (l *Levels) GetButton(menu, text string, data any) *Button {
 	for _, v := range l.Menu[menu].Buttons {
+		if !v.Button.Visible(data) {
+			continue
+		}
 		if text == strings.ToLower(strings.TrimSpace(v.Button.ButtonText)) || (v.Button.ButtonID != "" && text == v.Button.ButtonID) {
 			return &v.Button
 		}
 	}
 	return nil
 }
-
-// InjectLevels - Adds a levels to the Gin context
-func InjectLevels(key string, levels *Levels) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Set(key, levels)
-	}
-}
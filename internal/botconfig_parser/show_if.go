@@ -0,0 +1,54 @@
+package botconfig_parser
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"connect-text-bot/internal/logger"
+)
+
+// conditionTemplate - шаблон условия show_if, можно писать и без {{ }}: eq .User.CounterpartOwnerID "..."
+func conditionTemplate(showIf string) string {
+	if strings.Contains(showIf, "{{") {
+		return showIf
+	}
+	return "{{ " + showIf + " }}"
+}
+
+// Visible - выполняется ли условие show_if для данных пользователя data (те же, что в шаблонах текстов).
+// Условие не выполнено, если результат пустой, false, 0 или значения нет. Элемент без условия виден всегда,
+// с ошибкой в условии - скрыт
+func Visible(showIf string, data any) bool {
+	if showIf == "" {
+		return true
+	}
+
+	templ, err := template.New("show_if").Parse(conditionTemplate(showIf))
+	if err != nil {
+		logger.Warning("Ошибка в условии show_if", showIf, err)
+		return false
+	}
+
+	var out bytes.Buffer
+	if err := templ.Execute(&out, data); err != nil {
+		logger.Warning("Ошибка в условии show_if", showIf, err)
+		return false
+	}
+
+	switch strings.TrimSpace(out.String()) {
+	case "", "false", "0", "<no value>", "&lt;no value&gt;":
+		return false
+	}
+	return true
+}
+
+// Visible - показывать ли кнопку пользователю
+func (b *Button) Visible(data any) bool {
+	return Visible(b.ShowIf, data)
+}
+
+// Visible - отправлять ли сообщение пользователю
+func (a *Answer) Visible(data any) bool {
+	return Visible(a.ShowIf, data)
+}
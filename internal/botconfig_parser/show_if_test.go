@@ -0,0 +1,117 @@
+package botconfig_parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+type showIfData struct {
+	Var  map[string]string
+	Plan string
+	Paid bool
+}
+
+func TestVisible(t *testing.T) {
+	data := showIfData{Var: map[string]string{"city": "Москва", "empty": ""}, Plan: "pro", Paid: true}
+
+	tests := []struct {
+		showIf string
+		want   bool
+	}{
+		{"", true},
+		{`eq .Plan "pro"`, true},
+		{`eq .Plan "free"`, false},
+		{`{{ if .Paid }}yes{{ end }}`, true},
+		{`not .Paid`, false},
+		{`.Var.city`, true},
+		{`.Var.empty`, false},
+		// переменной нет
+		{`.Var.missing`, false},
+		{`and .Paid (eq (index .Var "city") "Москва")`, true},
+		{`len .Var`, true},
+		{`0`, false},
+		// ошибка в условии скрывает элемент
+		{`{{ .Plan`, false},
+		{`.Unknown`, false},
+	}
+	for _, tt := range tests {
+		if got := Visible(tt.showIf, data); got != tt.want {
+			t.Errorf("Visible(%q) = %v, want %v", tt.showIf, got, tt.want)
+		}
+	}
+}
+
+func TestGenKeyboardShowIf(t *testing.T) {
+	levels, err := LoadLevels(writeConfig(t, `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Всем"
+          goto: final_menu
+      - button:
+          id: 2
+          text: "Только pro"
+          show_if: eq .Plan "pro"
+          goto: final_menu
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := func(data any) (res []string) {
+		if kb := levels.GenKeyboard("start", data); kb != nil {
+			for _, row := range *kb {
+				res = append(res, row[0].Text)
+			}
+		}
+		return
+	}
+
+	if got := texts(showIfData{Plan: "pro"}); !slices.Equal(got, []string{"Всем", "Только pro"}) {
+		t.Errorf("keyboard for pro = %v", got)
+	}
+	if got := texts(showIfData{Plan: "free"}); !slices.Equal(got, []string{"Всем"}) {
+		t.Errorf("keyboard for free = %v", got)
+	}
+
+	// скрытую кнопку нельзя нажать
+	if b := levels.GetButton("start", "2", showIfData{Plan: "free"}); b != nil {
+		t.Errorf("hidden button pressed: %s", b.ButtonText)
+	}
+	if b := levels.GetButton("start", "2", showIfData{Plan: "pro"}); b == nil {
+		t.Error("visible button not found")
+	}
+}
+
+func TestShowIfChecks(t *testing.T) {
+	path := writeConfig(t, `menus:
+  start:
+    answer:
+      - chat: "Для pro"
+        show_if: eq .Plan "pro"
+    buttons:
+      - button:
+          id: 1
+          text: "Сломано"
+          show_if: "{{ eq .Plan"
+          goto: final_menu
+      - button:
+          id: 2
+          text: "Закрыть"
+          close_button: true
+`)
+
+	levels, errs := Validate(path, "./")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "show_if") {
+		t.Fatalf("errors = %v, want one show_if error", errs)
+	}
+
+	got := warningsByMenu(t, levels.Analyze())
+	if !hasWarning(got, "start", "клавиатура не будет отправлена") {
+		t.Errorf("menu with all answers hidden not reported: %v", got)
+	}
+}
@@ -0,0 +1,314 @@
+package botconfig_parser
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// ValidationError - ошибка найденная при проверке конфига с указанием места в файле
+type ValidationError struct {
+	// файл:строка поля с ошибкой или объявления меню, пустая если найти не удалось
+	Pos string
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Pos == "" {
+		return e.Err.Error()
+	}
+	return e.Pos + ": " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// Validate - проверить конфиг бота без запуска, возвращает все найденные ошибки
+func Validate(pathCnf, filesDir string) (*Levels, []error) {
+	levels, err := LoadLevels(pathCnf)
+	if levels == nil {
+		return nil, []error{err}
+	}
+
+	errs := unjoin(err)
+	errs = append(errs, levels.checkFiles(filesDir)...)
+	errs = append(errs, levels.checkTemplates()...)
+
+	return levels, WithPositions(pathCnf, errs)
+}
+
+// WithPositions - указать где в файлах находится поле с ошибкой, а если его найти не удалось - меню
+func WithPositions(pathCnf string, errs []error) []error {
+	menus := menuNodes(pathCnf)
+	for i, err := range errs {
+		var menuErr *MenuError
+		if errors.As(err, &menuErr) {
+			var pos string
+			if m, ok := menus[menuErr.Menu]; ok {
+				pos = m.position(menuErr.Path)
+			}
+			errs[i] = &ValidationError{Pos: pos, Err: err}
+		}
+	}
+	return errs
+}
+
+// разобрать объединенную ошибку на составляющие
+func unjoin(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// WalkButtons - обойти все кнопки всех меню, включая do_button и do_button у save_to_var
+func (l *Levels) WalkButtons(fn func(menu string, b *Button)) {
+	l.walkButtons(func(menu string, _ []string, b *Button) {
+		fn(menu, b)
+	})
+}
+
+// walkButtons - WalkButtons с путем к кнопке внутри меню
+func (l *Levels) walkButtons(fn func(menu string, path []string, b *Button)) {
+	var walk func(menu string, path []string, b *Button)
+	walk = func(menu string, path []string, b *Button) {
+		fn(menu, path, b)
+		if b.SaveToVar != nil && b.SaveToVar.DoButton != nil {
+			walk(menu, append(slices.Clone(path), "save_to_var", "do_button"), b.SaveToVar.DoButton)
+		}
+	}
+
+	for _, k := range l.menuKeys() {
+		v := l.Menu[k]
+		for i, b := range v.Buttons {
+			walk(k, buttonPath(i), &b.Button)
+		}
+		if v.DoButton != nil {
+			walk(k, []string{"do_button"}, v.DoButton)
+		}
+	}
+}
+
+// путь к полю внутри кнопки или меню
+func fieldPath(path []string, field ...any) []string {
+	path = slices.Clone(path)
+	for _, f := range field {
+		path = append(path, fmt.Sprint(f))
+	}
+	return path
+}
+
+// отсортированный список id меню
+func (l *Levels) menuKeys() []string {
+	keys := make([]string, 0, len(l.Menu))
+	for k := range l.Menu {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// проверить что все файлы из answer и chat существуют в files_dir
+func (l *Levels) checkFiles(filesDir string) (errs []error) {
+	checkAnswers := func(menu string, path []string, answers []*Answer) {
+		for i, a := range answers {
+			if a.File == "" {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(filesDir, a.File)); err != nil {
+				errs = append(errs, &MenuError{Menu: menu, Path: fieldPath(path, i, "file"), Err: fmt.Errorf("файл не найден в files_dir (%s): %s", filesDir, a.File)})
+			}
+		}
+	}
+
+	for _, k := range l.menuKeys() {
+		checkAnswers(k, []string{"answer"}, l.Menu[k].Answer)
+	}
+	l.walkButtons(func(menu string, path []string, b *Button) {
+		checkAnswers(menu, fieldPath(path, "chat"), b.Chat)
+	})
+	return
+}
+
+// проверить что все шаблоны разбираются
+func (l *Levels) checkTemplates() (errs []error) {
+	check := func(menu string, path []string, where, text string) {
+		if !strings.Contains(text, "{{") {
+			return
+		}
+		if _, err := template.New("cmd").Parse(text); err != nil {
+			errs = append(errs, &MenuError{Menu: menu, Path: path, Err: fmt.Errorf("ошибка в шаблоне (%s): %v", where, err)})
+		}
+	}
+	checkCondition := func(menu string, path []string, where, showIf string) {
+		if showIf == "" {
+			return
+		}
+		if _, err := template.New("show_if").Parse(conditionTemplate(showIf)); err != nil {
+			errs = append(errs, &MenuError{Menu: menu, Path: path, Err: fmt.Errorf("ошибка в условии show_if (%s): %v", where, err)})
+		}
+	}
+	checkAnswers := func(menu string, path []string, where string, answers []*Answer) {
+		for i, a := range answers {
+			check(menu, fieldPath(path, i, "chat"), where, a.Chat)
+			checkCondition(menu, fieldPath(path, i, "show_if"), where, a.ShowIf)
+		}
+	}
+
+	for _, k := range l.menuKeys() {
+		checkAnswers(k, []string{"answer"}, "answer", l.Menu[k].Answer)
+	}
+	l.walkButtons(func(menu string, path []string, b *Button) {
+		checkAnswers(menu, fieldPath(path, "chat"), "chat", b.Chat)
+		checkCondition(menu, fieldPath(path, "show_if"), "кнопка "+b.ButtonText, b.ShowIf)
+
+		if b.SaveToVar != nil {
+			if b.SaveToVar.SendText != nil {
+				check(menu, fieldPath(path, "save_to_var", "send_text"), "send_text", *b.SaveToVar.SendText)
+			}
+			for i, v := range b.SaveToVar.OfferOptions {
+				check(menu, fieldPath(path, "save_to_var", "offer_options", i), "offer_options", v)
+			}
+		}
+
+		if b.TicketButton != nil {
+			check(menu, fieldPath(path, "ticket_button", "ticket_info"), "ticket_info", b.TicketButton.TicketInfo)
+			if b.TicketButton.Data != nil {
+				if pt := b.TicketButton.Data.Theme; pt != nil && pt.DefaultValue != nil {
+					check(menu, fieldPath(path, "ticket_button", "data", "theme", "value"), "theme", *pt.DefaultValue)
+				}
+				if pt := b.TicketButton.Data.Description; pt != nil && pt.DefaultValue != nil {
+					check(menu, fieldPath(path, "ticket_button", "data", "description", "value"), "description", *pt.DefaultValue)
+				}
+			}
+		}
+	})
+	return
+}
+
+// menuNode - объявление меню в yaml файле
+type menuNode struct {
+	file string
+	// ключ или id меню
+	decl ast.Node
+	// содержимое меню
+	body ast.Node
+}
+
+// position - файл:строка поля по пути внутри меню. Если поле не найдено, например
+// значение взято из настроек по умолчанию, то строка ближайшего найденного родителя
+func (m menuNode) position(path []string) string {
+	pos, node := m.decl, m.body
+	for _, key := range path {
+		keyNode, value := child(node, key)
+		if keyNode == nil {
+			break
+		}
+		pos, node = keyNode, value
+	}
+	return fmt.Sprintf("%s:%d", m.file, pos.GetToken().Position.Line)
+}
+
+// child - элемент key узла: ключ отображения или индекс последовательности
+func child(node ast.Node, key string) (keyNode, value ast.Node) {
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		return child(n.Value, key)
+	case *ast.TagNode:
+		return child(n.Value, key)
+	case *ast.SequenceNode:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(n.Values) {
+			return nil, nil
+		}
+		return n.Values[i], n.Values[i]
+	}
+	for _, item := range mappingValues(node) {
+		if item.Key.GetToken().Value == key {
+			return item.Key, item.Value
+		}
+	}
+	return nil, nil
+}
+
+// найти объявления меню во всех yaml файлах каталога конфига
+func menuNodes(pathCnf string) map[string]menuNode {
+	files := []string{pathCnf}
+	_ = filepath.Walk(path.Dir(pathCnf), func(p string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && p != pathCnf && slices.Contains([]string{".yml", ".yaml"}, filepath.Ext(p)) {
+			files = append(files, p)
+		}
+		return nil
+	})
+
+	v := &menuVisitor{menus: make(map[string]menuNode)}
+	for _, f := range files {
+		file, err := parser.ParseFile(f, 0)
+		if err != nil {
+			continue
+		}
+		v.file = f
+		for _, doc := range file.Docs {
+			ast.Walk(v, doc)
+		}
+	}
+	return v.menus
+}
+
+type menuVisitor struct {
+	file  string
+	menus map[string]menuNode
+}
+
+func (v *menuVisitor) add(menu string, decl, body ast.Node) {
+	if _, exist := v.menus[menu]; !exist {
+		v.menus[menu] = menuNode{file: v.file, decl: decl, body: body}
+	}
+}
+
+func (v *menuVisitor) Visit(node ast.Node) ast.Visitor {
+	mv, ok := node.(*ast.MappingValueNode)
+	if !ok {
+		return v
+	}
+
+	switch mv.Key.GetToken().Value {
+	// меню верхнего уровня
+	case "menus":
+		for _, item := range mappingValues(mv.Value) {
+			v.add(item.Key.GetToken().Value, item.Key, item.Value)
+		}
+	// вложенное меню
+	case "menu":
+		for _, item := range mappingValues(mv.Value) {
+			if item.Key.GetToken().Value == "id" {
+				v.add(item.Value.GetToken().Value, item.Value, mv.Value)
+			}
+		}
+	}
+	return v
+}
+
+// получить пары ключ-значение узла
+func mappingValues(node ast.Node) []*ast.MappingValueNode {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return n.Values
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}
+	case *ast.AnchorNode:
+		return mappingValues(n.Value)
+	}
+	return nil
+}
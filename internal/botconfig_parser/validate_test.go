@@ -0,0 +1,134 @@
+package botconfig_parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfig - записать конфиг меню во временный каталог
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bot.yml")
+	if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const invalidConfig = `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Первая"
+          goto: final_menu
+      - button:
+          id: 2
+          text: "Вторая"
+          menu:
+            id: sub
+            answer:
+              - chat: "Sub"
+              - file: missing.pdf
+            buttons:
+              - button:
+                  id: 1
+                  text: "Никуда"
+                  goto: nowhere
+              - button:
+                  id: 2
+                  text: "Ввод"
+                  save_to_var:
+                    var_name: ""
+                    do_button:
+                      goto: start
+      - button:
+          id: 3
+          text: "Шаблон"
+          show_if: "{{ .Foo"
+          chat:
+            - chat: "ok"
+  empty:
+    answer:
+      - chat: "Пусто"
+`
+
+func TestValidatePositions(t *testing.T) {
+	path := writeConfig(t, invalidConfig)
+
+	_, errs := Validate(path, filepath.Dir(path))
+
+	var got []string
+	for _, err := range errs {
+		var vErr *ValidationError
+		if !errors.As(err, &vErr) {
+			t.Fatalf("error without position: %v", err)
+		}
+		got = append(got, filepath.Base(vErr.Pos))
+	}
+	slices.Sort(got)
+
+	// кнопка с goto, var_name, файл, show_if и меню без кнопок
+	want := []string{"bot.yml:17", "bot.yml:22", "bot.yml:27", "bot.yml:33", "bot.yml:36"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("positions = %v, want %v\nerrors: %v", got, want, errs)
+	}
+
+	text := errors.Join(errs...).Error()
+	for _, want := range []string{"несуществующий уровень", "missing.pdf", "отсутствуют кнопки: empty"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("errors do not mention %q: %s", want, text)
+		}
+	}
+}
+
+func TestMenuNodePositionFallback(t *testing.T) {
+	path := writeConfig(t, invalidConfig)
+	menus := menuNodes(path)
+
+	sub, ok := menus["sub"]
+	if !ok {
+		t.Fatal("nested menu not found")
+	}
+	tests := []struct {
+		path []string
+		want string
+	}{
+		{nil, path + ":14"},
+		{[]string{"buttons", "1", "button", "save_to_var", "do_button", "goto"}, path + ":29"},
+		// поля нет в конфиге - строка ближайшего родителя
+		{[]string{"buttons", "0", "button", "ticket_button", "channel_id"}, path + ":19"},
+		{[]string{"buttons", "5", "button"}, path + ":18"},
+	}
+	for _, tt := range tests {
+		if got := sub.position(tt.path); got != tt.want {
+			t.Errorf("position(%v) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestValidateValidConfig(t *testing.T) {
+	path := writeConfig(t, `menus:
+  start:
+    answer:
+      - chat: "Привет"
+    buttons:
+      - button:
+          id: 1
+          text: "Закрыть"
+          close_button: true
+`)
+	levels, errs := Validate(path, filepath.Dir(path))
+	if len(errs) != 0 {
+		t.Fatalf("errors for valid config: %v", errs)
+	}
+	if levels == nil || levels.Menu["start"] == nil {
+		t.Fatal("menu not loaded")
+	}
+}
@@ -0,0 +1,60 @@
+package botconfig_parser
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync/atomic"
+)
+
+// последняя выданная версия меню, растет с каждой успешной загрузкой любого конфига
+var lastVersion atomic.Uint64
+
+// Versioned - меню одного конфига. При перечитывании меню подменяется целиком,
+// сообщение обрабатывается с одной версией меню, полученной через Load
+type Versioned struct {
+	path string
+	cur  atomic.Pointer[Levels]
+}
+
+func newVersioned(path string, l *Levels) *Versioned {
+	v := &Versioned{path: path}
+	l.Version = lastVersion.Add(1)
+	v.cur.Store(l)
+	return v
+}
+
+// Load - текущая версия меню
+func (v *Versioned) Load() *Levels {
+	return v.cur.Load()
+}
+
+// Reload - перечитать конфиг. При ошибке остается последняя корректная версия меню
+func (v *Versioned) Reload() (Changes, error) {
+	old := v.Load()
+	l, err := LoadLevels(v.path)
+	if err != nil {
+		return Changes{Path: v.path, Version: old.Version, Hash: old.Hash, Error: err.Error()}, err
+	}
+	l.logWarnings()
+
+	l.Version = lastVersion.Add(1)
+	v.cur.Store(l)
+	return diffMenus(v.path, old, l), nil
+}
+
+// Loaded - проверка что меню загружено
+func (v *Versioned) Loaded(ctx context.Context) error {
+	return v.Load().Loaded(ctx)
+}
+
+// contentHash - хеш содержимого меню, пустой если меню не удалось сериализовать
+func (l *Levels) contentHash() string {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
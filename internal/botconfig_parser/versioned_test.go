@@ -0,0 +1,89 @@
+package botconfig_parser
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestVersionedReload(t *testing.T) {
+	path := writeConfig(t, menuConfig("Первая"))
+	l, err := LoadLevels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := newVersioned(path, l)
+	first := v.Load()
+
+	if err := os.WriteFile(path, []byte(menuConfig("Вторая")), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	second := v.Load()
+
+	// сообщение, начатое на первой версии, продолжает видеть её целиком
+	if got := first.Menu["start"].Answer[0].Chat; got != "Первая" {
+		t.Errorf("first version changed to %q", got)
+	}
+	if got := second.Menu["start"].Answer[0].Chat; got != "Вторая" {
+		t.Errorf("reloaded menu = %q", got)
+	}
+	if second.Version <= first.Version {
+		t.Errorf("version %d after %d", second.Version, first.Version)
+	}
+	if second.Hash == "" || second.Hash == first.Hash {
+		t.Errorf("hash %q after %q", second.Hash, first.Hash)
+	}
+
+	// тот же конфиг получает новую версию, но тот же хеш
+	if _, err := v.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v.Load().Version <= second.Version || v.Load().Hash != second.Hash {
+		t.Errorf("reload of same config: version %d, hash %q", v.Load().Version, v.Load().Hash)
+	}
+	second = v.Load()
+
+	// ошибка в конфиге оставляет последнюю корректную версию
+	if err := os.WriteFile(path, []byte("menus: текст\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Reload(); err == nil {
+		t.Fatal("broken config reloaded")
+	}
+	if v.Load() != second {
+		t.Fatal("menu replaced by broken config")
+	}
+}
+
+func TestVersionedConcurrentReload(t *testing.T) {
+	path := writeConfig(t, menuConfig("Меню"))
+	l, err := LoadLevels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := newVersioned(path, l)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := v.Reload(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if v.Load().Menu["start"] == nil {
+					t.Error("partially loaded menu")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -7,24 +7,20 @@ import (
 	"slices"
 
 	"connect-text-bot/internal/botconfig_parser"
-	"connect-text-bot/internal/connect/client"
 	"connect-text-bot/internal/database"
 	"connect-text-bot/internal/logger"
 
-	"github.com/allegro/bigcache/v3"
 	"github.com/google/uuid"
 )
 
-func (chatState *Chat) ChangeCache(cache *bigcache.BigCache, userID, lineID uuid.UUID) error {
+func (chatState *Chat) ChangeCache(cache database.StateStore, userID, lineID uuid.UUID) error {
 	data, err := json.Marshal(chatState)
 	if err != nil {
 		logger.Warning("Error while change state to cache", err)
 		return err
 	}
 
-	dbStateKey := userID.String() + ":" + lineID.String()
-
-	err = cache.Set(dbStateKey, data)
+	err = cache.Set(StateKey(userID, lineID), data)
 	logger.Debug("Write state to cache result")
 	if err != nil {
 		logger.Warning("Error while write state to cache", err)
@@ -33,7 +29,7 @@ func (chatState *Chat) ChangeCache(cache *bigcache.BigCache, userID, lineID uuid
 	return nil
 }
 
-func (chatState *Chat) ChangeCacheTicket(cache *bigcache.BigCache, userID, lineID uuid.UUID, key string, value database.TicketPart) error {
+func (chatState *Chat) ChangeCacheTicket(cache database.StateStore, userID, lineID uuid.UUID, key string, value database.TicketPart) error {
 	t := database.Ticket{}
 
 	switch key {
@@ -71,7 +67,7 @@ func (chatState *Chat) ChangeCacheTicket(cache *bigcache.BigCache, userID, lineI
 	return chatState.ChangeCache(cache, userID, lineID)
 }
 
-func (chatState *Chat) ChangeCacheVars(cache *bigcache.BigCache, userID, lineID uuid.UUID, key, value string) error {
+func (chatState *Chat) ChangeCacheVars(cache database.StateStore, userID, lineID uuid.UUID, key, value string) error {
 	if chatState.Vars == nil {
 		chatState.Vars = make(map[string]string)
 	}
@@ -80,16 +76,23 @@ func (chatState *Chat) ChangeCacheVars(cache *bigcache.BigCache, userID, lineID
 	return chatState.ChangeCache(cache, userID, lineID)
 }
 
-func (chatState *Chat) ChangeCacheSavedButton(cache *bigcache.BigCache, userID, lineID uuid.UUID, button *botconfig_parser.Button) error {
+func (chatState *Chat) ChangeCacheSavedButton(cache database.StateStore, userID, lineID uuid.UUID, button *botconfig_parser.Button) error {
 	chatState.SavedButton = button
 
 	return chatState.ChangeCache(cache, userID, lineID)
 }
 
-func (chatState *Chat) ChangeCacheState(cache *bigcache.BigCache, userID, lineID uuid.UUID, toState string) error {
+// ChangeCacheState - перейти в состояние toState на версии меню menuVersion
+func (chatState *Chat) ChangeCacheState(cache database.StateStore, userID, lineID uuid.UUID, toState string, menuVersion uint64) error {
 	if chatState.CurrentState == toState {
-		return nil
+		if chatState.MenuVersion == menuVersion {
+			return nil
+		}
+		// состояние то же, но меню обновилось
+		chatState.MenuVersion = menuVersion
+		return chatState.ChangeCache(cache, userID, lineID)
 	}
+	chatState.MenuVersion = menuVersion
 
 	chatState.PreviousState = chatState.CurrentState
 	chatState.CurrentState = toState
@@ -103,7 +106,7 @@ func (chatState *Chat) ChangeCacheState(cache *bigcache.BigCache, userID, lineID
 }
 
 // чистим необязательные поля хранимых данных
-func (chatState *Chat) ClearCacheOmitemptyFields(cache *bigcache.BigCache, userID, lineID uuid.UUID) error {
+func (chatState *Chat) ClearCacheOmitemptyFields(cache database.StateStore, userID, lineID uuid.UUID) error {
 	if _, exist := chatState.Vars[database.VAR_FOR_SAVE]; exist {
 		chatState.Vars[database.VAR_FOR_SAVE] = ""
 	}
@@ -114,7 +117,7 @@ func (chatState *Chat) ClearCacheOmitemptyFields(cache *bigcache.BigCache, userI
 }
 
 // сохранить данные о пользователе в кеше
-func (chatState *Chat) SaveUserDataInCache(cl *client.Client, ctx context.Context, cache *bigcache.BigCache, userID, lineID uuid.UUID) (err error) {
+func (chatState *Chat) SaveUserDataInCache(cl SubscriberGetter, ctx context.Context, cache database.StateStore, userID, lineID uuid.UUID) (err error) {
 	// получаем данные о пользователе
 	userData, err := cl.GetSubscriber(ctx, userID)
 	if err != nil {
@@ -127,7 +130,7 @@ func (chatState *Chat) SaveUserDataInCache(cl *client.Client, ctx context.Contex
 }
 
 // вернуться на предыдущий пункт меню в истории
-func (chatState *Chat) HistoryStateBack(cache *bigcache.BigCache, userID, lineID uuid.UUID) error {
+func (chatState *Chat) HistoryStateBack(cache database.StateStore, userID, lineID uuid.UUID) error {
 	// если истории нет то мы в старт должны быть
 	if len(chatState.HistoryState) == 0 {
 		chatState.PreviousState = database.GREETINGS
@@ -149,7 +152,7 @@ func (chatState *Chat) HistoryStateBack(cache *bigcache.BigCache, userID, lineID
 }
 
 // добавить новый пункт меню в историю
-func (chatState *Chat) HistoryStateAppend(cache *bigcache.BigCache, userID, lineID uuid.UUID, state string) error {
+func (chatState *Chat) HistoryStateAppend(cache database.StateStore, userID, lineID uuid.UUID, state string) error {
 	// чистим историю если меню последнее должно быть
 	if slices.Contains([]string{database.FAIL_QNA, database.FINAL, database.START, database.GREETINGS}, state) {
 		return chatState.HistoryStateClear(cache, userID, lineID)
@@ -169,7 +172,7 @@ func (chatState *Chat) HistoryStateAppend(cache *bigcache.BigCache, userID, line
 }
 
 // очистить историю и необязательные поля
-func (chatState *Chat) HistoryStateClear(cache *bigcache.BigCache, userID, lineID uuid.UUID) error {
+func (chatState *Chat) HistoryStateClear(cache database.StateStore, userID, lineID uuid.UUID) error {
 	chatState.HistoryState = []string{}
 
 	return chatState.ClearCacheOmitemptyFields(cache, userID, lineID)
@@ -4,26 +4,57 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
 	"connect-text-bot/internal/botconfig_parser"
-	"connect-text-bot/internal/connect/client"
 	"connect-text-bot/internal/connect/response"
 	"connect-text-bot/internal/database"
 	"connect-text-bot/internal/logger"
 
-	"github.com/allegro/bigcache/v3"
 	"github.com/google/uuid"
 )
 
-func GetState(cl *client.Client, ctx context.Context, cache *bigcache.BigCache, userID, lineID uuid.UUID) Chat {
-	var chatState Chat
+// ключ состояния пользователя на линии в хранилище
+func StateKey(userID, lineID uuid.UUID) string {
+	return userID.String() + ":" + lineID.String()
+}
+
+// разобрать ключ состояния на id пользователя и id линии
+func ParseStateKey(key string) (userID, lineID uuid.UUID, err error) {
+	u, l, ok := strings.Cut(key, ":")
+	if !ok {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("не корректный ключ состояния: %s", key)
+	}
+	if userID, err = uuid.Parse(u); err != nil {
+		return
+	}
+	lineID, err = uuid.Parse(l)
+	return
+}
+
+// LoadState - получить сохраненное состояние, если его нет то database.ErrEntryNotFound
+func LoadState(cache database.StateStore, userID, lineID uuid.UUID) (chatState Chat, err error) {
+	b, err := cache.Get(StateKey(userID, lineID))
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, &chatState)
+	return
+}
 
-	dbStateKey := userID.String() + ":" + lineID.String()
+// DeleteState - удалить состояние пользователя, при следующем сообщении диалог начнется заново
+func DeleteState(cache database.StateStore, userID, lineID uuid.UUID) error {
+	return cache.Delete(StateKey(userID, lineID))
+}
+
+func GetState(cl SubscriberGetter, ctx context.Context, cache database.StateStore, userID, lineID uuid.UUID) Chat {
+	var chatState Chat
 
-	b, err := cache.Get(dbStateKey)
+	b, err := cache.Get(StateKey(userID, lineID))
 	if err != nil {
-		if errors.Is(err, bigcache.ErrEntryNotFound) {
-			logger.Info("No state in cache for " + userID.String() + ":" + lineID.String())
+		if errors.Is(err, database.ErrEntryNotFound) {
+			logger.InfoCtx(ctx, "No state in cache for "+userID.String()+":"+lineID.String())
 			chatState = Chat{
 				PreviousState: database.GREETINGS,
 				CurrentState:  database.GREETINGS,
@@ -32,14 +63,14 @@ func GetState(cl *client.Client, ctx context.Context, cache *bigcache.BigCache,
 			// сохраняем пользовательские данные
 			err := chatState.SaveUserDataInCache(cl, ctx, cache, userID, lineID)
 			if err != nil {
-				logger.Warning("Error while get user data", err)
+				logger.WarningCtx(ctx, "Error while get user data", err)
 			}
 			return chatState
 		}
 	}
 	err = json.Unmarshal(b, &chatState)
 	if err != nil {
-		logger.Warning("Error while decoding state", err)
+		logger.WarningCtx(ctx, "Error while decoding state", err)
 	}
 
 	return chatState
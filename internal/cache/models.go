@@ -1,9 +1,13 @@
 package cache
 
 import (
+	"context"
+
 	"connect-text-bot/internal/botconfig_parser"
 	"connect-text-bot/internal/connect/response"
 	"connect-text-bot/internal/database"
+
+	"github.com/google/uuid"
 )
 
 type (
@@ -15,6 +19,8 @@ type (
 		PreviousState string `json:"prev_state" binding:"required" example:"100"`
 		// текущее состояние
 		CurrentState string `json:"curr_state" binding:"required" example:"300"`
+		// версия меню, на которой пользователь перешел в текущее состояние
+		MenuVersion uint64 `json:"menu_version,omitempty"`
 		// информация о пользователе
 		User response.User `json:"user"`
 
@@ -25,4 +31,9 @@ type (
 		// кнопка которую необходимо сохранить для последующей работы
 		SavedButton *botconfig_parser.Button `json:"saved_button" binding:"omitempty"`
 	}
+
+	// SubscriberGetter - получение данных пользователя из 1С-Коннект
+	SubscriberGetter interface {
+		GetSubscriber(ctx context.Context, userID uuid.UUID) (response.User, error)
+	}
 )
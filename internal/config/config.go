@@ -1,7 +1,13 @@
 package config
 
 import (
+	"connect-text-bot/internal/connect/client"
+	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/dedup"
+	"connect-text-bot/internal/health"
+	"connect-text-bot/internal/transcript"
 	"connect-text-bot/internal/us"
+	"connect-text-bot/internal/worker"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -17,16 +23,35 @@ type (
 		ConnectServer ConnectServer `yaml:"connect_server"`
 		UsServer      us.UsServer   `yaml:"us_server"`
 
-		FilesDir        string      `yaml:"files_dir"`
-		BotConfig       string      `yaml:"bot_config"`
-		SpecID          *uuid.UUID  `yaml:"spec_id"`
-		GeneralSettings bool        `yaml:"use_general_settings"`
-		Line            []uuid.UUID `yaml:"line"`
+		StateStore database.StateStoreConfig `yaml:"state_store"`
+		Workers    worker.Config             `yaml:"workers"`
+		Dedup      dedup.Config              `yaml:"dedup"`
+		Admin      Admin                     `yaml:"admin"`
+		Health     health.Config             `yaml:"health"`
+		Transcript transcript.Config         `yaml:"transcript"`
+
+		FilesDir        string       `yaml:"files_dir"`
+		BotConfig       string       `yaml:"bot_config"`
+		SpecID          *uuid.UUID   `yaml:"spec_id"`
+		GeneralSettings bool         `yaml:"use_general_settings"`
+		Line            []LineConfig `yaml:"line"`
 	}
 
 	Server struct {
 		Host   string `yaml:"host"`
 		Listen string `yaml:"listen"`
+
+		// секрет, который добавляется к адресу хука и проверяется при получении сообщений
+		HookToken string `yaml:"hook_token"`
+		// адреса и подсети, с которых разрешено принимать сообщения
+		AllowedIPs []string `yaml:"allowed_ips"`
+		// адреса и подсети прокси, которым можно доверять заголовок X-Forwarded-For
+		TrustedProxies []string `yaml:"trusted_proxies"`
+	}
+
+	Admin struct {
+		// токен для доступа к /admin, если пустой то методы не регистрируются
+		Token string `yaml:"token"`
 	}
 
 	Connect struct {
@@ -36,6 +61,13 @@ type (
 
 	ConnectServer struct {
 		Addr string `yaml:"addr"`
+
+		// Повтор запросов при временных ошибках
+		Retry client.RetryConfig `yaml:"retry"`
+		// Отключение запросов к методу API, который постоянно отвечает ошибкой
+		CircuitBreaker client.BreakerConfig `yaml:"circuit_breaker"`
+		// Ограничение частоты запросов по линии и сообщений одному пользователю
+		RateLimit client.RateLimitConfig `yaml:"rate_limit"`
 	}
 )
 
@@ -44,3 +76,13 @@ func Inject(key string, cnf *Conf) gin.HandlerFunc {
 		c.Set(key, cnf)
 	}
 }
+
+// Redacted - копия настроек со скрытыми паролями и токенами, для вывода в лог
+func (cnf Conf) Redacted() Conf {
+	for _, secret := range []*string{&cnf.Connect.Password, &cnf.Server.HookToken, &cnf.Admin.Token, &cnf.StateStore.Password} {
+		if *secret != "" {
+			*secret = "***"
+		}
+	}
+	return cnf
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	cnf, err := loadTestConfig(t, `server:
+  listen: :8080
+  hook_token: hook-secret
+connect:
+  login: bot
+  password: connect-secret
+admin:
+  token: admin-secret
+state_store:
+  type: redis
+  password: redis-secret
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := fmt.Sprint(cnf.Redacted())
+	for _, secret := range []string{"hook-secret", "connect-secret", "admin-secret", "redis-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s in %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, ":8080") || !strings.Contains(out, "bot") {
+		t.Errorf("settings lost: %s", out)
+	}
+
+	// исходные настройки не меняются
+	if cnf.Admin.Token != "admin-secret" || cnf.StateStore.Password != "redis-secret" {
+		t.Errorf("original config changed: %+v", cnf)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Diff - настройки, значения которых в a и b отличаются, в виде пути по ключам yaml, например server.listen
+func Diff(a, b *Conf) (changed []string) {
+	diffValues("", reflect.ValueOf(*a), reflect.ValueOf(*b), &changed)
+	return
+}
+
+func diffValues(path string, a, b reflect.Value, changed *[]string) {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*changed = append(*changed, path)
+		}
+		return
+	}
+
+	for i := 0; i < a.NumField(); i++ {
+		f := a.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+		diffValues(name, a.Field(i), b.Field(i), changed)
+	}
+}
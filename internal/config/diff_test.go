@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := loadTestConfig(t, `server:
+  listen: :8080
+  allowed_ips: [10.0.0.0/8]
+workers:
+  drain_timeout: 5s
+line:
+  - 00000000-0000-0000-0000-000000000001
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := *a
+	if got := Diff(a, &b); len(got) != 0 {
+		t.Fatalf("same config: %v", got)
+	}
+
+	b.Server.Listen = ":8081"
+	b.Server.AllowedIPs = []string{"10.0.0.0/8", "127.0.0.1"}
+	b.Workers.DrainTimeout = time.Second
+	b.Line = nil
+	if got := strings.Join(Diff(a, &b), ","); got != "server.listen,server.allowed_ips,workers.drain_timeout,line" {
+		t.Errorf("diff = %s", got)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// LineConfig - линия поддержки. В config.yml можно указать только id линии,
+// тогда используются общие настройки, или отдельные настройки бота для линии
+type LineConfig struct {
+	ID uuid.UUID `yaml:"id"`
+	// Конфиг меню линии, по умолчанию общий (--bot)
+	BotConfig string `yaml:"bot_config"`
+	// Специалист, от лица которого работает бот на линии, по умолчанию spec_id
+	SpecID *uuid.UUID `yaml:"spec_id"`
+	// Директория с файлами меню линии, по умолчанию files_dir
+	FilesDir string `yaml:"files_dir"`
+}
+
+func (l *LineConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var id string
+	if err := unmarshal(&id); err == nil {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("некорректный id линии %q: %w", id, err)
+		}
+		*l = LineConfig{ID: parsed}
+		return nil
+	}
+
+	type plain LineConfig
+	return unmarshal((*plain)(l))
+}
+
+// SetLineDefaults - заполнить незаданные настройки линий общими
+func (cnf *Conf) SetLineDefaults() {
+	for i := range cnf.Line {
+		l := &cnf.Line[i]
+		if l.BotConfig == "" {
+			l.BotConfig = cnf.BotConfig
+		}
+		if l.SpecID == nil {
+			l.SpecID = cnf.SpecID
+		}
+		if l.FilesDir == "" {
+			l.FilesDir = cnf.FilesDir
+		}
+	}
+}
+
+// BotConfigs - конфиги меню всех линий без повторов
+func (cnf *Conf) BotConfigs() (paths []string) {
+	seen := make(map[string]bool)
+	for _, l := range cnf.Line {
+		if !seen[l.BotConfig] {
+			seen[l.BotConfig] = true
+			paths = append(paths, l.BotConfig)
+		}
+	}
+	return
+}
+
+// Equal - совпадают ли все настройки линий
+func (l LineConfig) Equal(o LineConfig) bool {
+	sameSpec := l.SpecID == o.SpecID || (l.SpecID != nil && o.SpecID != nil && *l.SpecID == *o.SpecID)
+	return l.ID == o.ID && l.BotConfig == o.BotConfig && l.FilesDir == o.FilesDir && sameSpec
+}
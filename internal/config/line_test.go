@@ -0,0 +1,91 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func loadTestConfig(t *testing.T, content string) (*Conf, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+	cnf := &Conf{}
+	return cnf, LoadConfig(path, cnf)
+}
+
+func TestLineConfig(t *testing.T) {
+	cnf, err := loadTestConfig(t, `bot_config: ./bot.yml
+files_dir: ./files
+spec_id: 00000000-0000-0000-0000-00000000000a
+line:
+  - 00000000-0000-0000-0000-000000000001
+  - id: 00000000-0000-0000-0000-000000000002
+    bot_config: ./sales/bot.yml
+    spec_id: 00000000-0000-0000-0000-00000000000b
+    files_dir: ./sales/files
+  - id: 00000000-0000-0000-0000-000000000003
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cnf.SetLineDefaults()
+
+	spec := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	salesSpec := uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	want := []LineConfig{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), BotConfig: "./bot.yml", SpecID: &spec, FilesDir: "./files"},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), BotConfig: "./sales/bot.yml", SpecID: &salesSpec, FilesDir: "./sales/files"},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), BotConfig: "./bot.yml", SpecID: &spec, FilesDir: "./files"},
+	}
+	if len(cnf.Line) != len(want) {
+		t.Fatalf("lines = %+v", cnf.Line)
+	}
+	for i, l := range cnf.Line {
+		w := want[i]
+		if l.ID != w.ID || l.BotConfig != w.BotConfig || l.FilesDir != w.FilesDir || l.SpecID == nil || *l.SpecID != *w.SpecID {
+			t.Errorf("line %d = %+v, want %+v", i, l, w)
+		}
+	}
+
+	// линии с общим конфигом используют одно меню
+	if got := cnf.BotConfigs(); !slices.Equal(got, []string{"./bot.yml", "./sales/bot.yml"}) {
+		t.Errorf("BotConfigs() = %v", got)
+	}
+}
+
+func TestLineConfigInvalidID(t *testing.T) {
+	_, err := loadTestConfig(t, "line:\n  - not-a-uuid\n")
+	if err == nil || !strings.Contains(err.Error(), "некорректный id линии") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLineConfigEqual(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	a2 := a
+	line := LineConfig{ID: uuid.New(), BotConfig: "./bot.yml", SpecID: &a}
+
+	same := line
+	same.SpecID = &a2
+	if !line.Equal(same) {
+		t.Error("lines with equal spec_id differ")
+	}
+
+	for _, changed := range []LineConfig{
+		{ID: line.ID, BotConfig: "./other.yml", SpecID: &a},
+		{ID: line.ID, BotConfig: "./bot.yml", SpecID: &b},
+		{ID: line.ID, BotConfig: "./bot.yml"},
+		{ID: line.ID, BotConfig: "./bot.yml", SpecID: &a, FilesDir: "./files"},
+	} {
+		if line.Equal(changed) {
+			t.Errorf("%+v equals %+v", line, changed)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 
 	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/worker"
 
 	"github.com/goccy/go-yaml"
 )
@@ -14,16 +16,23 @@ const CONNECT_SOAP_SERVER = "https://cus.1c-connect.com/cus/ws/PartnerWebAPI2"
 func GetConfig(configPath string, cnf *Conf) {
 	logger.Debug("Loading configuration")
 
+	if err := LoadConfig(configPath, cnf); err != nil {
+		logger.Crit(err)
+	}
+}
+
+// LoadConfig - прочитать конфиг и заполнить значения по умолчанию
+func LoadConfig(configPath string, cnf *Conf) error {
 	input, err := os.Open(configPath)
 	if err != nil {
-		logger.Crit("Error while reading config!")
+		return fmt.Errorf("Error while reading config! %w", err)
 	}
 	defer input.Close()
 
 	decoder := yaml.NewDecoder(input)
 	err = decoder.Decode(cnf)
 	if err != nil {
-		logger.Crit("Error while decoding config!")
+		return fmt.Errorf("Error while decoding config! %w", err)
 	}
 	if cnf.ConnectServer.Addr == "" {
 		cnf.ConnectServer.Addr = CONNECT_SERVER
@@ -31,4 +40,8 @@ func GetConfig(configPath string, cnf *Conf) {
 	if cnf.UsServer.Addr == "" {
 		cnf.UsServer.Addr = CONNECT_SOAP_SERVER
 	}
+	if cnf.Workers.DrainTimeout <= 0 {
+		cnf.Workers.DrainTimeout = worker.DEFAULT_DRAIN_TIMEOUT
+	}
+	return nil
 }
@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/connect/requests"
+	"connect-text-bot/internal/connect/response"
+
+	"github.com/google/uuid"
+)
+
+// ConnectAPI - методы API 1С-Коннект которые использует бот, в тестах подменяется на connecttest.Fake
+type ConnectAPI interface {
+	SetHook(hookAddr string) (content []byte, err error)
+	DeleteHook() (content []byte, err error)
+
+	Start(ctx context.Context, userID uuid.UUID) error
+	Send(ctx context.Context, userID uuid.UUID, text string, keyboard *[][]requests.KeyboardKey) error
+	SendFile(ctx context.Context, userID uuid.UUID, isImage bool, fileName string, filePath string, comment *string, keyboard *[][]requests.KeyboardKey) error
+	DropKeyboard(ctx context.Context, userID uuid.UUID) error
+
+	CloseTreatment(ctx context.Context, userID uuid.UUID) error
+	RerouteTreatment(ctx context.Context, userID uuid.UUID) error
+	Reroute(ctx context.Context, userID, toLineID uuid.UUID, quote string) error
+	AppointSpec(ctx context.Context, userID uuid.UUID, authorID *uuid.UUID, appointSpec uuid.UUID) error
+
+	GetQNA(ctx context.Context, userID uuid.UUID, skipGreetings, skipGoodbyes bool) *messages.AutofaqRequestBody
+	QnaSelected(ctx context.Context, requestID, resultID uuid.UUID)
+
+	GetSubscriber(ctx context.Context, userID uuid.UUID) (response.User, error)
+	GetSubscriptions(ctx context.Context, userID, lineID uuid.UUID) (response.Subscriptions, error)
+	GetSpecialist(ctx context.Context, specID uuid.UUID) (response.User, error)
+	GetSpecialists(ctx context.Context, lineID uuid.UUID) (response.Users, error)
+	GetSpecialistAvailable(ctx context.Context, specID uuid.UUID) (bool, error)
+	GetSpecialistsAvailable(ctx context.Context) ([]uuid.UUID, error)
+
+	GetTicket(ctx context.Context, id uuid.UUID) (response.Ticket, error)
+	GetTicketData(ctx context.Context, userCounterpartOwnerID uuid.UUID) (response.GetTicketDataResponse, error)
+	GetTicketDataKinds(ctx context.Context, ticketData *response.GetTicketDataResponse, userCounterpartOwnerID uuid.UUID) ([]response.TicketDataKind, error)
+	GetTicketDataTypesWhereKind(ctx context.Context, ticketData *response.GetTicketDataResponse, userCounterpartOwnerID uuid.UUID, kindID uuid.UUID) ([]response.TicketDataType, error)
+}
+
+var _ ConnectAPI = (*Client)(nil)
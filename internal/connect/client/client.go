@@ -8,11 +8,13 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"connect-text-bot/internal/connect/requests"
 	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/metrics"
 
 	"github.com/google/uuid"
 )
@@ -29,7 +31,8 @@ type (
 
 		specID *uuid.UUID
 
-		cl *http.Client
+		cl    *http.Client
+		pacer *pacer
 	}
 
 	HttpError struct {
@@ -60,6 +63,8 @@ func New(lineID uuid.UUID, server_addr, login, password string, generalSettings
 				DisableCompression:  true,
 			},
 		},
+
+		pacer: newPacer(policy.rate),
 	}
 }
 
@@ -85,43 +90,93 @@ func (c *Client) DeleteHook() (content []byte, err error) {
 	return c.Invoke(context.Background(), http.MethodDelete, "/hook/bot/"+c.lineID.String()+"/", nil, "application/json", nil)
 }
 
+// Ping - проверить доступность API 1С-Коннект
+func (c *Client) Ping(ctx context.Context) error {
+	// одна попытка в обход ограничения частоты и circuit breaker: проверка не должна
+	// ни ждать очереди запросов линии, ни открывать breaker для сообщений пользователей
+	_, _, err := c.invoke(ctx, http.MethodGet, "line", nil, "application/json", nil)
+	return err
+}
+
+// Invoke - выполнить запрос к API, при временных ошибках запрос повторяется (см. RetryConfig)
 func (c *Client) Invoke(ctx context.Context, method string, methodUrl string, urlParams url.Values, contentType string, body []byte) (content []byte, err error) {
 	methodUrl = strings.Trim(methodUrl, "/")
+	endpoint := metrics.Endpoint(methodUrl)
+
+	b := getBreaker(method + " " + endpoint)
+	retry := policy.retry
+
+	for attempt := 0; ; attempt++ {
+		if err := c.pacer.waitLine(ctx); err != nil {
+			return nil, err
+		}
+		if !b.allow() {
+			metrics.CircuitRejected.WithLabelValues(method, endpoint).Inc()
+			return nil, fmt.Errorf("%w: %s /%s/", ErrCircuitOpen, method, methodUrl)
+		}
+
+		var retryAfter time.Duration
+		content, retryAfter, err = c.invoke(ctx, method, methodUrl, urlParams, contentType, body)
+		b.done(ctx, err)
+
+		if err == nil || ctx.Err() != nil || attempt+1 >= retry.Attempts || !isRetryable(method, err) {
+			return
+		}
+
+		// Retry-After учитываем, но дольше MaxDelay не ждем
+		delay := min(max(retry.backoff(attempt), retryAfter), retry.MaxDelay)
+		logger.WarningCtx(ctx, "Retry request", method, methodUrl, "in", delay, "after error:", err)
+		metrics.InvokeRetries.WithLabelValues(method, endpoint).Inc()
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// invoke - одна попытка запроса, для 429 и 503 возвращает значение Retry-After
+func (c *Client) invoke(ctx context.Context, method string, methodUrl string, urlParams url.Values, contentType string, body []byte) (content []byte, retryAfter time.Duration, err error) {
 	reqUrl := c.serverAddr + "/v1/" + methodUrl + "/"
 	if urlParams != nil {
 		reqUrl += "?" + urlParams.Encode()
 	}
 
-	req, err := http.NewRequest(method, reqUrl, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, method, reqUrl, bytes.NewBuffer(body))
 	if err != nil {
-		logger.Warning("Error while create request for", reqUrl, "with method", method, ":", err)
+		logger.WarningCtx(ctx, "Error while create request for", reqUrl, "with method", method, ":", err)
+		return nil, 0, err
 	}
 
 	req.SetBasicAuth(c.login, c.password)
 	req.Header.Set("Content-Type", contentType)
 
-	logger.Debug("---> request", req.Method, reqUrl)
+	logger.DebugCtx(ctx, "---> request", req.Method, reqUrl)
 
+	start := time.Now()
 	resp, err := c.cl.Do(req)
 
 	if err != nil {
-		return nil, err
+		metrics.InvokeDuration.WithLabelValues(method, metrics.Endpoint(methodUrl), "error").Observe(time.Since(start).Seconds())
+		return nil, 0, err
 	} else {
 		defer resp.Body.Close()
 		bodyBytes, err := io.ReadAll(resp.Body)
-		logger.Debug("<--- request", req.Method, reqUrl, "with body", bodyBytes)
+		metrics.InvokeDuration.WithLabelValues(method, metrics.Endpoint(methodUrl), strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
+		logger.DebugCtx(ctx, "<--- request", req.Method, reqUrl, "with body", bodyBytes)
 		if err != nil {
-			logger.Warning("Error while read response body", err)
+			logger.WarningCtx(ctx, "Error while read response body", err)
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			return nil, &HttpError{
+			return nil, parseRetryAfter(resp.Header.Get("Retry-After")), &HttpError{
 				Url:     req.URL.String(),
 				Code:    resp.StatusCode,
 				Message: string(bodyBytes),
 			}
 		}
 
-		return bodyBytes, nil
+		return bodyBytes, 0, nil
 	}
 }
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+)
+
+const (
+	DEFAULT_LINE_RATE     = 10
+	DEFAULT_LINE_BURST    = 10
+	DEFAULT_USER_INTERVAL = 250 * time.Millisecond
+	DEFAULT_USER_BURST    = 1
+
+	// через сколько забываем ограничение пользователя, которому ничего не отправляли
+	userLimiterIdle = 10 * time.Minute
+)
+
+type (
+	// RateLimitConfig - ограничение частоты запросов к API, чтобы не упираться в лимиты 1С-Коннект
+	RateLimitConfig struct {
+		// Запросов в секунду на одну линию
+		LineRate float64 `yaml:"line_rate"`
+		// Сколько запросов по линии можно отправить подряд без ожидания
+		LineBurst int `yaml:"line_burst"`
+		// Интервал между сообщениями одному пользователю, чтобы сообщения приходили по порядку
+		UserInterval time.Duration `yaml:"user_interval"`
+		// Сколько сообщений пользователю можно отправить подряд без ожидания
+		UserBurst int `yaml:"user_burst"`
+	}
+
+	// pacer - token bucket на линию и на каждого пользователя линии
+	pacer struct {
+		line *rate.Limiter
+
+		mu        sync.Mutex
+		users     map[uuid.UUID]*userLimiter
+		lastSweep time.Time
+		userRate  rate.Limit
+		userBurst int
+	}
+
+	userLimiter struct {
+		*rate.Limiter
+		lastUsed time.Time
+	}
+)
+
+func (r *RateLimitConfig) setDefaults() {
+	if r.LineRate <= 0 {
+		r.LineRate = DEFAULT_LINE_RATE
+	}
+	if r.LineBurst <= 0 {
+		r.LineBurst = DEFAULT_LINE_BURST
+	}
+	if r.UserInterval <= 0 {
+		r.UserInterval = DEFAULT_USER_INTERVAL
+	}
+	if r.UserBurst <= 0 {
+		r.UserBurst = DEFAULT_USER_BURST
+	}
+}
+
+func newPacer(cnf RateLimitConfig) *pacer {
+	return &pacer{
+		line:      rate.NewLimiter(rate.Limit(cnf.LineRate), cnf.LineBurst),
+		users:     make(map[uuid.UUID]*userLimiter),
+		lastSweep: time.Now(),
+		userRate:  rate.Every(cnf.UserInterval),
+		userBurst: cnf.UserBurst,
+	}
+}
+
+// waitLine - дождаться возможности отправить запрос по линии
+func (p *pacer) waitLine(ctx context.Context) error {
+	return p.line.Wait(ctx)
+}
+
+// waitUser - дождаться возможности отправить сообщение пользователю
+func (p *pacer) waitUser(ctx context.Context, userID uuid.UUID) error {
+	p.mu.Lock()
+	now := time.Now()
+	if now.Sub(p.lastSweep) > userLimiterIdle {
+		for id, l := range p.users {
+			if now.Sub(l.lastUsed) > userLimiterIdle {
+				delete(p.users, id)
+			}
+		}
+		p.lastSweep = now
+	}
+
+	l, ok := p.users[userID]
+	if !ok {
+		l = &userLimiter{Limiter: rate.NewLimiter(p.userRate, p.userBurst)}
+		p.users[userID] = l
+	}
+	l.lastUsed = now
+	p.mu.Unlock()
+
+	return l.Wait(ctx)
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPacerUserInterval(t *testing.T) {
+	p := newPacer(RateLimitConfig{LineRate: 1000, LineBurst: 1000, UserInterval: 30 * time.Millisecond, UserBurst: 1})
+	ctx := context.Background()
+	user := uuid.New()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := p.waitUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Fatalf("3 messages to one user took %v, want at least 2 intervals", elapsed)
+	}
+
+	// другому пользователю ждать не нужно
+	start = time.Now()
+	if err := p.waitUser(ctx, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("first message to other user waited %v", elapsed)
+	}
+}
+
+func TestPacerLineRate(t *testing.T) {
+	p := newPacer(RateLimitConfig{LineRate: 50, LineBurst: 2, UserInterval: time.Millisecond, UserBurst: 1})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := p.waitLine(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 запроса сразу, еще 2 по 20ms
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("4 requests took %v, want at least 40ms", elapsed)
+	}
+}
+
+func TestPacerWaitCanceled(t *testing.T) {
+	p := newPacer(RateLimitConfig{LineRate: 1000, LineBurst: 1000, UserInterval: time.Hour, UserBurst: 1})
+	user := uuid.New()
+
+	if err := p.waitUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// ожидание дольше дедлайна не начинается
+	start := time.Now()
+	if err := p.waitUser(ctx, user); err == nil {
+		t.Fatal("wait longer than context deadline succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("canceled wait took %v", elapsed)
+	}
+}
+
+func TestPacerForgetsIdleUsers(t *testing.T) {
+	p := newPacer(RateLimitConfig{LineRate: 1000, LineBurst: 1000, UserInterval: time.Millisecond, UserBurst: 1})
+	ctx := context.Background()
+	idle, active := uuid.New(), uuid.New()
+
+	_ = p.waitUser(ctx, idle)
+	p.users[idle].lastUsed = time.Now().Add(-2 * userLimiterIdle)
+	p.lastSweep = time.Now().Add(-2 * userLimiterIdle)
+
+	_ = p.waitUser(ctx, active)
+	if _, ok := p.users[idle]; ok {
+		t.Error("idle user limiter not removed")
+	}
+	if _, ok := p.users[active]; !ok {
+		t.Error("active user limiter removed")
+	}
+}
+
+func TestRateLimitDefaults(t *testing.T) {
+	var cnf RateLimitConfig
+	cnf.setDefaults()
+	if cnf.LineRate != DEFAULT_LINE_RATE || cnf.LineBurst != DEFAULT_LINE_BURST || cnf.UserInterval != DEFAULT_USER_INTERVAL || cnf.UserBurst != DEFAULT_USER_BURST {
+		t.Fatalf("defaults = %+v", cnf)
+	}
+}
@@ -22,21 +22,21 @@ func (c Client) GetQNA(ctx context.Context, userID uuid.UUID, skipGreetings, ski
 
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		logger.Warning("text - GetQNA", err)
+		logger.WarningCtx(ctx, "text - GetQNA", err)
 	}
 
 	body, err := c.Invoke(ctx, http.MethodPost, "/line/qna/", nil, "application/json", jsonData)
 	if err != nil {
-		logger.Warning("text - GetQNA", err)
+		logger.WarningCtx(ctx, "text - GetQNA", err)
 	}
 
 	err = json.Unmarshal(body, &resp)
 	if err != nil {
-		logger.Warning("text - GetQNA", err)
+		logger.WarningCtx(ctx, "text - GetQNA", err)
 	}
 
 	// Debug
-	logger.Debug("text - GetQNA", resp)
+	logger.DebugCtx(ctx, "text - GetQNA", resp)
 
 	return resp
 }
@@ -50,11 +50,11 @@ func (c Client) QnaSelected(ctx context.Context, requestID, resultID uuid.UUID)
 
 	jsonData, err := json.Marshal(data)
 	if err != nil {
-		logger.Warning("text - GetQNA", err)
+		logger.WarningCtx(ctx, "text - GetQNA", err)
 	}
 
 	body, err := c.Invoke(ctx, http.MethodPut, "/line/qna/selected/", nil, "application/json", jsonData)
 	if err != nil {
-		logger.Warning("text - QnaSelected", err, body)
+		logger.WarningCtx(ctx, "text - QnaSelected", err, body)
 	}
 }
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"connect-text-bot/internal/logger"
+	"connect-text-bot/internal/metrics"
+)
+
+const (
+	DEFAULT_RETRY_ATTEMPTS   = 3
+	DEFAULT_RETRY_BASE_DELAY = 200 * time.Millisecond
+	DEFAULT_RETRY_MAX_DELAY  = 5 * time.Second
+
+	DEFAULT_BREAKER_THRESHOLD = 5
+	DEFAULT_BREAKER_COOLDOWN  = 30 * time.Second
+)
+
+// состояния circuit breaker, значения совпадают с метрикой circuit_breaker_state
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// метод API временно отключен после серии ошибок
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type (
+	// RetryConfig - повтор запросов при временных ошибках
+	RetryConfig struct {
+		// Сколько всего попыток, 1 - без повторов
+		Attempts int `yaml:"attempts"`
+		// Задержка перед первым повтором, далее удваивается
+		BaseDelay time.Duration `yaml:"base_delay"`
+		// Максимальная задержка между попытками
+		MaxDelay time.Duration `yaml:"max_delay"`
+	}
+
+	// BreakerConfig - отключение запросов к методу API, который постоянно отвечает ошибкой
+	BreakerConfig struct {
+		// Сколько ошибок подряд отключают метод
+		Threshold int `yaml:"threshold"`
+		// Через сколько отключенный метод пробуем снова
+		Cooldown time.Duration `yaml:"cooldown"`
+	}
+
+	breaker struct {
+		mu       sync.Mutex
+		name     string
+		state    int
+		failures int
+		openedAt time.Time
+		// в состоянии half-open пропускаем только один пробный запрос
+		probing bool
+	}
+)
+
+var (
+	policy = struct {
+		retry   RetryConfig
+		breaker BreakerConfig
+		rate    RateLimitConfig
+	}{
+		retry:   RetryConfig{Attempts: DEFAULT_RETRY_ATTEMPTS, BaseDelay: DEFAULT_RETRY_BASE_DELAY, MaxDelay: DEFAULT_RETRY_MAX_DELAY},
+		breaker: BreakerConfig{Threshold: DEFAULT_BREAKER_THRESHOLD, Cooldown: DEFAULT_BREAKER_COOLDOWN},
+		rate:    RateLimitConfig{LineRate: DEFAULT_LINE_RATE, LineBurst: DEFAULT_LINE_BURST, UserInterval: DEFAULT_USER_INTERVAL, UserBurst: DEFAULT_USER_BURST},
+	}
+
+	breakersMu sync.Mutex
+	// circuit breaker по методу API, общие для всех линий
+	breakers = make(map[string]*breaker)
+)
+
+// Configure - задать настройки повторов, circuit breaker и частоты запросов для всех клиентов,
+// незаполненные берутся по умолчанию. Вызывается до создания клиентов
+func Configure(retry RetryConfig, b BreakerConfig, rl RateLimitConfig) {
+	if retry.Attempts <= 0 {
+		retry.Attempts = DEFAULT_RETRY_ATTEMPTS
+	}
+	if retry.BaseDelay <= 0 {
+		retry.BaseDelay = DEFAULT_RETRY_BASE_DELAY
+	}
+	if retry.MaxDelay <= 0 {
+		retry.MaxDelay = DEFAULT_RETRY_MAX_DELAY
+	}
+	if b.Threshold <= 0 {
+		b.Threshold = DEFAULT_BREAKER_THRESHOLD
+	}
+	if b.Cooldown <= 0 {
+		b.Cooldown = DEFAULT_BREAKER_COOLDOWN
+	}
+
+	rl.setDefaults()
+
+	policy.retry = retry
+	policy.breaker = b
+	policy.rate = rl
+}
+
+// задержка перед повтором: экспоненциальная с разбросом от половины до полной
+func (r RetryConfig) backoff(attempt int) time.Duration {
+	delay := min(r.BaseDelay<<attempt, r.MaxDelay)
+	if delay <= 0 {
+		delay = r.MaxDelay
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// значение заголовка Retry-After в секундах или в виде даты
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// можно ли повторить запрос. 429 - запрос не обработан, повторяем любой.
+// Остальные ошибки повторяем только для идемпотентных методов, POST - только если соединение не установлено
+func isRetryable(method string, err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		if httpErr.Code == http.StatusTooManyRequests {
+			return true
+		}
+		return httpErr.Code >= http.StatusInternalServerError && isIdempotent(method)
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return isIdempotent(method)
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// ошибка говорит о проблеме на стороне сервера: сеть, таймаут клиента, 5xx или 429
+func isServerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code >= http.StatusInternalServerError || httpErr.Code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func getBreaker(name string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[name]
+	if !ok {
+		b = &breaker{name: name}
+		breakers[name] = b
+	}
+	return b
+}
+
+// allow - можно ли выполнить запрос
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < policy.breaker.Cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// done - учесть результат запроса. Если контекст вызывающего завершен, запрос только освобождает
+// пробный запрос, состояние не меняется: по нему нельзя судить, восстановился ли сервер.
+// Таймаут самого http клиента контекст не завершает и считается ошибкой сервера
+func (b *breaker) done(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	if !isServerFailure(err) {
+		b.failures = 0
+		if b.state != breakerClosed {
+			logger.Info("Circuit breaker closed for", b.name)
+			b.setState(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= policy.breaker.Threshold {
+		if b.state != breakerOpen {
+			logger.Warning("Circuit breaker opened for", b.name, "after", b.failures, "failures, retry in", policy.breaker.Cooldown)
+		}
+		b.openedAt = time.Now()
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *breaker) setState(state int) {
+	b.state = state
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(state))
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errServer = &HttpError{Code: http.StatusBadGateway}
+
+func testPolicy(t *testing.T, retry RetryConfig, b BreakerConfig) {
+	t.Helper()
+	prev := policy
+	Configure(retry, b, RateLimitConfig{LineRate: 1000, LineBurst: 1000})
+	t.Cleanup(func() { policy = prev })
+}
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	testPolicy(t, RetryConfig{}, BreakerConfig{Threshold: 3, Cooldown: time.Hour})
+	b := &breaker{name: "test"}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if !b.allow() {
+			t.Fatalf("request %d rejected before threshold", i)
+		}
+		b.done(ctx, errServer)
+	}
+	// успешный запрос сбрасывает счетчик ошибок
+	b.allow()
+	b.done(ctx, nil)
+	for i := 0; i < 2; i++ {
+		b.allow()
+		b.done(ctx, errServer)
+	}
+	if b.state != breakerClosed {
+		t.Fatalf("state = %d after failures below threshold, want closed", b.state)
+	}
+
+	b.allow()
+	b.done(ctx, errServer)
+	if b.state != breakerOpen {
+		t.Fatalf("state = %d after threshold, want open", b.state)
+	}
+	if b.allow() {
+		t.Fatal("open breaker allowed request")
+	}
+}
+
+func TestBreakerClientErrorIsSuccess(t *testing.T) {
+	testPolicy(t, RetryConfig{}, BreakerConfig{Threshold: 1, Cooldown: time.Hour})
+	b := &breaker{name: "test"}
+
+	b.allow()
+	b.done(context.Background(), &HttpError{Code: http.StatusBadRequest})
+	if b.state != breakerClosed {
+		t.Fatalf("state = %d after 400, want closed", b.state)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	testPolicy(t, RetryConfig{}, BreakerConfig{Threshold: 1, Cooldown: time.Millisecond})
+	ctx := context.Background()
+
+	open := func(t *testing.T) *breaker {
+		b := &breaker{name: "test"}
+		b.allow()
+		b.done(ctx, errServer)
+		time.Sleep(2 * time.Millisecond)
+		if !b.allow() {
+			t.Fatal("probe rejected after cooldown")
+		}
+		if b.state != breakerHalfOpen {
+			t.Fatalf("state = %d after cooldown, want half-open", b.state)
+		}
+		if b.allow() {
+			t.Fatal("second request allowed while probing")
+		}
+		return b
+	}
+
+	t.Run("probe succeeded", func(t *testing.T) {
+		b := open(t)
+		b.done(ctx, nil)
+		if b.state != breakerClosed {
+			t.Fatalf("state = %d, want closed", b.state)
+		}
+	})
+
+	t.Run("probe failed", func(t *testing.T) {
+		b := open(t)
+		b.done(ctx, errServer)
+		if b.state != breakerOpen {
+			t.Fatalf("state = %d, want open", b.state)
+		}
+	})
+
+	t.Run("probe canceled", func(t *testing.T) {
+		b := open(t)
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		b.done(canceled, context.Canceled)
+		if b.state != breakerHalfOpen {
+			t.Fatalf("state = %d, want half-open", b.state)
+		}
+		if !b.allow() {
+			t.Fatal("next probe rejected after canceled probe")
+		}
+	})
+}
+
+func TestBreakerClientTimeoutIsFailure(t *testing.T) {
+	testPolicy(t, RetryConfig{}, BreakerConfig{Threshold: 1, Cooldown: time.Hour})
+	b := &breaker{name: "test"}
+
+	// таймаут http клиента тоже context.DeadlineExceeded, но контекст вызывающего жив
+	b.allow()
+	b.done(context.Background(), context.DeadlineExceeded)
+	if b.state != breakerOpen {
+		t.Fatalf("state = %d after client timeout, want open", b.state)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %v", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)); got < 59*time.Minute {
+		t.Errorf("parseRetryAfter(date) = %v", got)
+	}
+	for _, v := range []string{"", "-1", "soon"} {
+		if got := parseRetryAfter(v); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", v, got)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		method string
+		err    error
+		want   bool
+	}{
+		{http.MethodPost, &HttpError{Code: http.StatusTooManyRequests}, true},
+		{http.MethodPost, &HttpError{Code: http.StatusBadGateway}, false},
+		{http.MethodGet, &HttpError{Code: http.StatusBadGateway}, true},
+		{http.MethodGet, &HttpError{Code: http.StatusNotFound}, false},
+		{http.MethodGet, ErrCircuitOpen, false},
+		{http.MethodPost, errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryable(tt.method, tt.err); got != tt.want {
+			t.Errorf("isRetryable(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestInvokeRetryAfterClampedToMaxDelay(t *testing.T) {
+	testPolicy(t, RetryConfig{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: 50 * time.Millisecond}, BreakerConfig{Threshold: 100})
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := New(uuid.New(), srv.URL, "", "", false, nil)
+	start := time.Now()
+	content, err := c.Invoke(context.Background(), http.MethodPost, "/clamp/", nil, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "ok" || calls.Load() != 2 {
+		t.Fatalf("content = %q after %d calls", content, calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("retry waited %v, want at most MaxDelay", elapsed)
+	}
+}
+
+func TestInvokeRetriesServerErrors(t *testing.T) {
+	testPolicy(t, RetryConfig{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}, BreakerConfig{Threshold: 100})
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	c := New(uuid.New(), srv.URL, "", "", false, nil)
+	content, err := c.Invoke(context.Background(), http.MethodGet, "/retry/", nil, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "ok" || calls.Load() != 3 {
+		t.Fatalf("content = %q after %d calls", content, calls.Load())
+	}
+
+	// POST с ответом 5xx не повторяется
+	calls.Store(0)
+	if _, err := c.Invoke(context.Background(), http.MethodPost, "/retry/", nil, "application/json", nil); err == nil {
+		t.Fatal("POST with 503 succeeded")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("POST with 503 sent %d times", calls.Load())
+	}
+}
+
+func TestPingBypassesPacerAndBreaker(t *testing.T) {
+	prev := policy
+	Configure(RetryConfig{Attempts: 3}, BreakerConfig{Threshold: 1, Cooldown: time.Hour}, RateLimitConfig{LineRate: 0.01, LineBurst: 1})
+	t.Cleanup(func() { policy = prev })
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(uuid.New(), srv.URL, "", "", false, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// проверки не ждут очереди линии, не повторяются и не открывают breaker
+	for i := 0; i < 3; i++ {
+		if err := c.Ping(ctx); err == nil {
+			t.Fatal("Ping of failing server succeeded")
+		}
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("server called %d times, want 3", calls.Load())
+	}
+	if b := getBreaker(http.MethodGet + " line"); b.state != breakerClosed {
+		t.Fatalf("breaker state = %d after failed pings, want closed", b.state)
+	}
+}
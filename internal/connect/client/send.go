@@ -17,6 +17,10 @@ import (
 )
 
 func (c Client) Start(ctx context.Context, userID uuid.UUID) error {
+	if err := c.pacer.waitUser(ctx, userID); err != nil {
+		return err
+	}
+
 	data := requests.DropKeyboardRequest{
 		LineID: c.lineID,
 		UserID: userID,
@@ -34,6 +38,10 @@ func (c Client) Start(ctx context.Context, userID uuid.UUID) error {
 
 // Скрыть цифровое меню
 func (c Client) DropKeyboard(ctx context.Context, userID uuid.UUID) (err error) {
+	if err = c.pacer.waitUser(ctx, userID); err != nil {
+		return
+	}
+
 	data := requests.TreatmentRequest{
 		LineID: c.lineID,
 		UserID: userID,
@@ -50,6 +58,10 @@ func (c Client) DropKeyboard(ctx context.Context, userID uuid.UUID) (err error)
 
 // Отправить сообщение в чат
 func (c Client) Send(ctx context.Context, userID uuid.UUID, text string, keyboard *[][]requests.KeyboardKey) error {
+	if err := c.pacer.waitUser(ctx, userID); err != nil {
+		return err
+	}
+
 	data := requests.MessageRequest{
 		LineID:          c.lineID,
 		UserID:          userID,
@@ -71,6 +83,10 @@ func (c Client) Send(ctx context.Context, userID uuid.UUID, text string, keyboar
 
 // Метод позволяет отправить файл или изображение в чат
 func (c Client) SendFile(ctx context.Context, userID uuid.UUID, isImage bool, fileName string, filePath string, comment *string, keyboard *[][]requests.KeyboardKey) error {
+	if err := c.pacer.waitUser(ctx, userID); err != nil {
+		return err
+	}
+
 	data := requests.FileRequest{
 		LineID:          c.lineID,
 		UserID:          userID,
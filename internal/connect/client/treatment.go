@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"connect-text-bot/internal/connect/requests"
 
@@ -29,7 +28,10 @@ func (c Client) RerouteTreatment(ctx context.Context, userID uuid.UUID) error {
 
 // Закрыть текущее обращение
 func (c Client) CloseTreatment(ctx context.Context, userID uuid.UUID) error {
-	time.Sleep(500 * time.Millisecond)
+	// закрываем после уже отправленных пользователю сообщений
+	if err := c.pacer.waitUser(ctx, userID); err != nil {
+		return err
+	}
 
 	data := requests.TreatmentRequest{
 		LineID: c.lineID,
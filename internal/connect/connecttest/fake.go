@@ -0,0 +1,264 @@
+// Package connecttest - заглушка API 1С-Коннект для тестов, записывает все вызовы
+package connecttest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"connect-text-bot/internal/connect/client"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/connect/requests"
+	"connect-text-bot/internal/connect/response"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// Call - записанный вызов API
+	Call struct {
+		// имя метода ConnectAPI
+		Method string
+		UserID uuid.UUID
+		// Send - текст сообщения, SendFile - подпись к файлу, SetHook - адрес
+		Text string
+		// SendFile - имя файла
+		FileName string
+		Keyboard *[][]requests.KeyboardKey
+		// Reroute - линия, AppointSpec - специалист, GetSpecialist, GetTicket - запрошенный id
+		ID uuid.UUID
+	}
+
+	// Fake - заглушка API 1С-Коннект, ответы задаются полями, вызовы сохраняются по порядку
+	Fake struct {
+		mu    sync.Mutex
+		calls []Call
+
+		LineID uuid.UUID
+
+		// ответ GetSubscriber, UserID подставляется из запроса
+		Subscriber response.User
+		// ответ GetSubscriptions, если nil - пользователь подключен к любой линии
+		Subscriptions response.Subscriptions
+		// ответ GetSpecialists, GetSpecialist ищет среди них
+		Specialists response.Users
+		// свободные специалисты
+		AvailableSpecs []uuid.UUID
+		// ответ GetQNA, если nil - база знаний без ответа
+		QNA *messages.AutofaqRequestBody
+		// ответ GetTicketData
+		TicketData response.GetTicketDataResponse
+
+		// ошибки которые вернет метод с указанным именем
+		Errors map[string]error
+	}
+)
+
+var _ client.ConnectAPI = (*Fake)(nil)
+
+func New(lineID uuid.UUID) *Fake {
+	return &Fake{
+		LineID: lineID,
+		Errors: make(map[string]error),
+	}
+}
+
+// Record - записать вызов, в т.ч. к другим заглушкам чтобы сохранить общий порядок
+func (f *Fake) Record(c Call) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, c)
+}
+
+func (f *Fake) record(c Call) error {
+	f.Record(c)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Errors[c.Method]
+}
+
+// Calls - все записанные вызовы по порядку
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.calls)
+}
+
+// CallsTo - записанные вызовы указанного метода
+func (f *Fake) CallsTo(method string) (calls []Call) {
+	for _, c := range f.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return
+}
+
+// Sent - тексты отправленных сообщений по порядку
+func (f *Fake) Sent() (texts []string) {
+	for _, c := range f.CallsTo("Send") {
+		texts = append(texts, c.Text)
+	}
+	return
+}
+
+// Reset - забыть записанные вызовы
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+func (f *Fake) SetHook(hookAddr string) ([]byte, error) {
+	return nil, f.record(Call{Method: "SetHook", Text: hookAddr})
+}
+
+func (f *Fake) DeleteHook() ([]byte, error) {
+	return nil, f.record(Call{Method: "DeleteHook"})
+}
+
+func (f *Fake) Start(_ context.Context, userID uuid.UUID) error {
+	return f.record(Call{Method: "Start", UserID: userID})
+}
+
+func (f *Fake) Send(_ context.Context, userID uuid.UUID, text string, keyboard *[][]requests.KeyboardKey) error {
+	return f.record(Call{Method: "Send", UserID: userID, Text: text, Keyboard: keyboard})
+}
+
+func (f *Fake) SendFile(_ context.Context, userID uuid.UUID, _ bool, fileName string, _ string, comment *string, keyboard *[][]requests.KeyboardKey) error {
+	c := Call{Method: "SendFile", UserID: userID, FileName: fileName, Keyboard: keyboard}
+	if comment != nil {
+		c.Text = *comment
+	}
+	return f.record(c)
+}
+
+func (f *Fake) DropKeyboard(_ context.Context, userID uuid.UUID) error {
+	return f.record(Call{Method: "DropKeyboard", UserID: userID})
+}
+
+func (f *Fake) CloseTreatment(_ context.Context, userID uuid.UUID) error {
+	return f.record(Call{Method: "CloseTreatment", UserID: userID})
+}
+
+func (f *Fake) RerouteTreatment(_ context.Context, userID uuid.UUID) error {
+	return f.record(Call{Method: "RerouteTreatment", UserID: userID})
+}
+
+func (f *Fake) Reroute(_ context.Context, userID, toLineID uuid.UUID, quote string) error {
+	return f.record(Call{Method: "Reroute", UserID: userID, ID: toLineID, Text: quote})
+}
+
+func (f *Fake) AppointSpec(_ context.Context, userID uuid.UUID, _ *uuid.UUID, appointSpec uuid.UUID) error {
+	return f.record(Call{Method: "AppointSpec", UserID: userID, ID: appointSpec})
+}
+
+func (f *Fake) GetQNA(_ context.Context, userID uuid.UUID, _, _ bool) *messages.AutofaqRequestBody {
+	_ = f.record(Call{Method: "GetQNA", UserID: userID})
+	if f.QNA == nil {
+		return &messages.AutofaqRequestBody{}
+	}
+	return f.QNA
+}
+
+func (f *Fake) QnaSelected(_ context.Context, requestID, resultID uuid.UUID) {
+	_ = f.record(Call{Method: "QnaSelected", ID: resultID})
+}
+
+func (f *Fake) GetSubscriber(_ context.Context, userID uuid.UUID) (response.User, error) {
+	user := f.Subscriber
+	user.UserID = userID
+	return user, f.record(Call{Method: "GetSubscriber", UserID: userID})
+}
+
+func (f *Fake) GetSubscriptions(_ context.Context, userID, lineID uuid.UUID) (response.Subscriptions, error) {
+	err := f.record(Call{Method: "GetSubscriptions", UserID: userID, ID: lineID})
+	if f.Subscriptions == nil {
+		return response.Subscriptions{{LineID: lineID, UserID: userID}}, err
+	}
+
+	var r response.Subscriptions
+	for _, s := range f.Subscriptions {
+		if s.LineID == lineID {
+			r = append(r, s)
+		}
+	}
+	return r, err
+}
+
+func (f *Fake) GetSpecialist(_ context.Context, specID uuid.UUID) (response.User, error) {
+	err := f.record(Call{Method: "GetSpecialist", ID: specID})
+	for _, s := range f.Specialists {
+		if s.UserID == specID {
+			return s, err
+		}
+	}
+	return response.User{UserID: specID}, err
+}
+
+func (f *Fake) GetSpecialists(_ context.Context, lineID uuid.UUID) (response.Users, error) {
+	return f.Specialists, f.record(Call{Method: "GetSpecialists", ID: lineID})
+}
+
+func (f *Fake) GetSpecialistAvailable(_ context.Context, specID uuid.UUID) (bool, error) {
+	return slices.Contains(f.AvailableSpecs, specID), f.record(Call{Method: "GetSpecialistAvailable", ID: specID})
+}
+
+func (f *Fake) GetSpecialistsAvailable(_ context.Context) ([]uuid.UUID, error) {
+	return f.AvailableSpecs, f.record(Call{Method: "GetSpecialistsAvailable"})
+}
+
+func (f *Fake) GetTicket(_ context.Context, id uuid.UUID) (response.Ticket, error) {
+	return response.Ticket{ID: id}, f.record(Call{Method: "GetTicket", ID: id})
+}
+
+func (f *Fake) GetTicketData(_ context.Context, userCounterpartOwnerID uuid.UUID) (response.GetTicketDataResponse, error) {
+	data := f.TicketData
+	data.CounterpartID = userCounterpartOwnerID
+	return data, f.record(Call{Method: "GetTicketData", ID: userCounterpartOwnerID})
+}
+
+// GetTicketDataKinds - виды услуг доступные по линии, как в client.Client
+func (f *Fake) GetTicketDataKinds(ctx context.Context, ticketData *response.GetTicketDataResponse, userCounterpartOwnerID uuid.UUID) (kinds []response.TicketDataKind, err error) {
+	if ticketData == nil {
+		ticketData = new(response.GetTicketDataResponse)
+		*ticketData, err = f.GetTicketData(ctx, userCounterpartOwnerID)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, kind := range ticketData.Kinds {
+		if slices.Contains(kind.Lines, f.LineID) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return
+}
+
+// GetTicketDataTypesWhereKind - виды работ по услуге, как в client.Client
+func (f *Fake) GetTicketDataTypesWhereKind(ctx context.Context, ticketData *response.GetTicketDataResponse, userCounterpartOwnerID uuid.UUID, kindID uuid.UUID) (types []response.TicketDataType, err error) {
+	kinds, err := f.GetTicketDataKinds(ctx, ticketData, userCounterpartOwnerID)
+	if err != nil {
+		return
+	}
+	if ticketData == nil {
+		ticketData = &f.TicketData
+	}
+
+	for _, kind := range kinds {
+		if kind.ID != kindID {
+			continue
+		}
+		for _, kindType := range kind.Types {
+			for _, t := range ticketData.Types {
+				if t.ID == kindType {
+					types = append(types, t)
+				}
+			}
+		}
+		break
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package connecttest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"connect-text-bot/internal/connect/requests"
+	"connect-text-bot/internal/connect/response"
+
+	"github.com/google/uuid"
+)
+
+func TestFakeRecordsCalls(t *testing.T) {
+	ctx := context.Background()
+	f := New(uuid.New())
+	user, line := uuid.New(), uuid.New()
+	keyboard := &[][]requests.KeyboardKey{{{ID: "1", Text: "Да"}}}
+
+	_ = f.Send(ctx, user, "Привет", keyboard)
+	_ = f.Reroute(ctx, user, line, "")
+	_ = f.Send(ctx, user, "Пока", nil)
+	_ = f.CloseTreatment(ctx, user)
+
+	var methods []string
+	for _, c := range f.Calls() {
+		methods = append(methods, c.Method)
+	}
+	if !slices.Equal(methods, []string{"Send", "Reroute", "Send", "CloseTreatment"}) {
+		t.Fatalf("calls = %v", methods)
+	}
+	if got := f.Sent(); !slices.Equal(got, []string{"Привет", "Пока"}) {
+		t.Errorf("Sent() = %v", got)
+	}
+	if calls := f.CallsTo("Reroute"); len(calls) != 1 || calls[0].ID != line || calls[0].UserID != user {
+		t.Errorf("CallsTo(Reroute) = %v", calls)
+	}
+	if c := f.CallsTo("Send")[0]; c.Keyboard != keyboard {
+		t.Errorf("keyboard not recorded: %v", c.Keyboard)
+	}
+
+	f.Reset()
+	if calls := f.Calls(); len(calls) != 0 {
+		t.Errorf("calls after Reset = %v", calls)
+	}
+}
+
+func TestFakeErrors(t *testing.T) {
+	f := New(uuid.New())
+	errSend := errors.New("send failed")
+	f.Errors["Send"] = errSend
+
+	if err := f.Send(context.Background(), uuid.New(), "текст", nil); !errors.Is(err, errSend) {
+		t.Fatalf("Send = %v, want %v", err, errSend)
+	}
+	if err := f.DropKeyboard(context.Background(), uuid.New()); err != nil {
+		t.Fatalf("DropKeyboard = %v", err)
+	}
+	// вызов с ошибкой тоже записывается
+	if len(f.CallsTo("Send")) != 1 {
+		t.Fatalf("failed call not recorded: %v", f.Calls())
+	}
+}
+
+func TestFakeResponses(t *testing.T) {
+	ctx := context.Background()
+	line, other := uuid.New(), uuid.New()
+	f := New(line)
+	f.Subscriber = response.User{Name: "Иван"}
+
+	user := uuid.New()
+	if u, _ := f.GetSubscriber(ctx, user); u.Name != "Иван" || u.UserID != user {
+		t.Errorf("GetSubscriber = %+v", u)
+	}
+	// без Subscriptions пользователь подключен к любой линии
+	if s, _ := f.GetSubscriptions(ctx, user, other); len(s) != 1 || s[0].LineID != other {
+		t.Errorf("GetSubscriptions = %v", s)
+	}
+
+	typeID := uuid.New()
+	f.TicketData = response.GetTicketDataResponse{
+		Kinds: []response.TicketDataKind{
+			{ID: uuid.New(), Name: "Своя линия", Types: []uuid.UUID{typeID}, Lines: []uuid.UUID{line}},
+			{ID: uuid.New(), Name: "Чужая линия", Lines: []uuid.UUID{other}},
+		},
+		Types: []response.TicketDataType{{ID: typeID, Name: "Консультация"}},
+	}
+	kinds, err := f.GetTicketDataKinds(ctx, nil, uuid.Nil)
+	if err != nil || len(kinds) != 1 || kinds[0].Name != "Своя линия" {
+		t.Fatalf("GetTicketDataKinds = %v, %v", kinds, err)
+	}
+	types, err := f.GetTicketDataTypesWhereKind(ctx, nil, uuid.Nil, kinds[0].ID)
+	if err != nil || len(types) != 1 || types[0].Name != "Консультация" {
+		t.Fatalf("GetTicketDataTypesWhereKind = %v, %v", types, err)
+	}
+}
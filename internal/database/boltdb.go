@@ -0,0 +1,143 @@
+package database
+
+import (
+	"encoding/binary"
+	"time"
+
+	"connect-text-bot/internal/logger"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var stateBucket = []byte("state")
+
+// хранилище состояний в файле на диске, переживает перезапуск бота
+type boltStore struct {
+	db       *bolt.DB
+	lifetime time.Duration
+
+	done chan struct{}
+}
+
+func ConnectBoltStore(path string, lifetime time.Duration) (StateStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(stateBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	s := &boltStore{
+		db:       db,
+		lifetime: lifetime,
+		done:     make(chan struct{}),
+	}
+
+	// удаляем устаревшие состояния при запуске и далее периодически
+	s.cleanup()
+	go func() {
+		ticker := time.NewTicker(lifetime)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.cleanup()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// первые 8 байт значения - время истечения в unix nano
+func (s *boltStore) encode(data []byte) []byte {
+	v := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(v, uint64(time.Now().Add(s.lifetime).UnixNano()))
+	copy(v[8:], data)
+	return v
+}
+
+func isExpired(v []byte) bool {
+	return len(v) < 8 || int64(binary.BigEndian.Uint64(v)) < time.Now().UnixNano()
+}
+
+func (s *boltStore) Get(key string) (data []byte, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(stateBucket).Get([]byte(key))
+		if v == nil || isExpired(v) {
+			return ErrEntryNotFound
+		}
+		// значение валидно только внутри транзакции
+		data = make([]byte, len(v)-8)
+		copy(data, v[8:])
+		return nil
+	})
+	return
+}
+
+func (s *boltStore) Set(key string, data []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Put([]byte(key), s.encode(data))
+	})
+}
+
+func (s *boltStore) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).Delete([]byte(key))
+	})
+}
+
+func (s *boltStore) Keys() (keys []string, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).ForEach(func(k, v []byte) error {
+			if !isExpired(v) {
+				keys = append(keys, string(k))
+			}
+			return nil
+		})
+	})
+	return
+}
+
+func (s *boltStore) Close() error {
+	close(s.done)
+	return s.db.Close()
+}
+
+// удалить все устаревшие состояния
+func (s *boltStore) cleanup() {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(stateBucket)
+
+		// удаление во время обхода курсором пропускает элементы, поэтому сначала собираем ключи
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if isExpired(v) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warning("Error while cleanup state store", err)
+	}
+}
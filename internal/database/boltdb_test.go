@@ -0,0 +1,112 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	s, err := ConnectBoltStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("user", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("deleted", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// состояния переживают перезапуск
+	s, err = ConnectBoltStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	b, err := s.Get("user")
+	if err != nil || string(b) != "state" {
+		t.Fatalf("Get after reopen = %q, %v", b, err)
+	}
+	if _, err := s.Get("deleted"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Get of deleted key: %v", err)
+	}
+}
+
+func TestBoltStoreLifetime(t *testing.T) {
+	s, err := ConnectBoltStore(filepath.Join(t.TempDir(), "state.db"), 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Set("user", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if err := s.Set("fresh", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get("user"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Get of expired key: %v", err)
+	}
+	keys, err := s.Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"fresh"}) {
+		t.Fatalf("keys = %q, want only fresh", keys)
+	}
+}
+
+func TestBoltStoreCleanup(t *testing.T) {
+	s, err := ConnectBoltStore(filepath.Join(t.TempDir(), "state.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	store := s.(*boltStore)
+
+	// устаревшие записи подряд: удаление во время обхода не должно их пропускать
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := s.Set(key, []byte("state")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.lifetime = -time.Second
+	for _, key := range []string{"b", "c", "d"} {
+		if err := s.Set(key, []byte("state")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store.cleanup()
+
+	var stored []string
+	err = store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(stateBucket).ForEach(func(k, _ []byte) error {
+			stored = append(stored, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stored, []string{"a"}) {
+		t.Fatalf("stored after cleanup = %q, want only a", stored)
+	}
+}
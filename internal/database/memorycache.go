@@ -1,24 +1,61 @@
 package database
 
 import (
+	"errors"
 	"time"
 
 	"connect-text-bot/internal/logger"
 
 	"github.com/allegro/bigcache/v3"
-	"github.com/gin-gonic/gin"
 )
 
-func ConnectInMemoryCache() *bigcache.BigCache {
-	cache, err := bigcache.NewBigCache(bigcache.DefaultConfig(2 * time.Hour))
+// хранилище состояний в памяти процесса, теряется при перезапуске
+type memoryStore struct {
+	cache *bigcache.BigCache
+}
+
+func ConnectInMemoryCache(lifetime time.Duration) StateStore {
+	cache, err := bigcache.NewBigCache(bigcache.DefaultConfig(lifetime))
 	if err != nil {
 		logger.Crit(err)
 	}
-	return cache
+	return &memoryStore{cache: cache}
+}
+
+func (s *memoryStore) Get(key string) ([]byte, error) {
+	b, err := s.cache.Get(key)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil, ErrEntryNotFound
+	}
+	return b, err
+}
+
+func (s *memoryStore) Set(key string, data []byte) error {
+	return s.cache.Set(key, data)
 }
 
-func InjectInMemoryCache(key string, cache *bigcache.BigCache) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Set(key, cache)
+func (s *memoryStore) Delete(key string) error {
+	err := s.cache.Delete(key)
+	if errors.Is(err, bigcache.ErrEntryNotFound) {
+		return nil
 	}
+	return err
+}
+
+func (s *memoryStore) Keys() ([]string, error) {
+	keys := make([]string, 0, s.cache.Len())
+
+	it := s.cache.Iterator()
+	for it.SetNext() {
+		e, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, e.Key())
+	}
+	return keys, nil
+}
+
+func (s *memoryStore) Close() error {
+	return s.cache.Close()
 }
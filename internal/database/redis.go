@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// таймаут одной операции с redis
+const redisTimeout = 5 * time.Second
+
+// общее хранилище состояний в redis, позволяет запускать несколько экземпляров бота
+type redisStore struct {
+	rdb      *redis.Client
+	prefix   string
+	lifetime time.Duration
+}
+
+func ConnectRedisStore(cnf StateStoreConfig) (StateStore, error) {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cnf.Addr,
+		Username: cnf.Username,
+		Password: cnf.Password,
+		DB:       cnf.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		_ = rdb.Close()
+		return nil, err
+	}
+
+	return &redisStore{
+		rdb:      rdb,
+		prefix:   cnf.Prefix,
+		lifetime: cnf.Lifetime,
+	}, nil
+}
+
+func (s *redisStore) Get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	b, err := s.rdb.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrEntryNotFound
+	}
+	return b, err
+}
+
+func (s *redisStore) Set(key string, data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.rdb.Set(ctx, s.prefix+key, data, s.lifetime).Err()
+}
+
+func (s *redisStore) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.rdb.Del(ctx, s.prefix+key).Err()
+}
+
+func (s *redisStore) Keys() (keys []string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	it := s.rdb.Scan(ctx, 0, globEscape(s.prefix)+"*", 100).Iterator()
+	for it.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(it.Val(), s.prefix))
+	}
+	return keys, it.Err()
+}
+
+// globEscape - экранировать спецсимволы шаблона SCAN MATCH, чтобы префикс совпадал буквально
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *redisStore) Close() error {
+	return s.rdb.Close()
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedis(t *testing.T, mr *miniredis.Miniredis, prefix string) StateStore {
+	t.Helper()
+	s, err := ConnectRedisStore(StateStoreConfig{Addr: mr.Addr(), Prefix: prefix, Lifetime: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newTestRedis(t, mr, "bot:")
+
+	if _, err := s.Get("user"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Get of missing key: %v", err)
+	}
+	if err := s.Set("user", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Get("user")
+	if err != nil || string(b) != "state" {
+		t.Fatalf("Get = %q, %v", b, err)
+	}
+	if !mr.Exists("bot:user") {
+		t.Fatal("key stored without prefix")
+	}
+
+	if err := s.Delete("user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("user"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+}
+
+func TestRedisStoreLifetime(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := newTestRedis(t, mr, "bot:")
+
+	if err := s.Set("user", []byte("state")); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("bot:user"); ttl != time.Hour {
+		t.Fatalf("ttl = %v, want 1h", ttl)
+	}
+
+	mr.FastForward(time.Hour + time.Second)
+	if _, err := s.Get("user"); !errors.Is(err, ErrEntryNotFound) {
+		t.Fatalf("Get of expired key: %v", err)
+	}
+}
+
+func TestRedisStoreKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	// префиксы со спецсимволами шаблона не должны захватывать чужие ключи
+	stores := map[string]StateStore{}
+	for _, prefix := range []string{"bot1:", "bot*:", "bot?:", "bot[1]:", `bot\:`} {
+		stores[prefix] = newTestRedis(t, mr, prefix)
+		for _, key := range []string{"a", "b"} {
+			if err := stores[prefix].Set(key, []byte(prefix)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	for prefix, s := range stores {
+		keys, err := s.Keys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, []string{"a", "b"}) {
+			t.Errorf("keys of %q = %q", prefix, keys)
+		}
+
+		b, err := s.Get("a")
+		if err != nil || string(b) != prefix {
+			t.Errorf("Get with prefix %q = %q, %v", prefix, b, err)
+		}
+	}
+}
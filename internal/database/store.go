@@ -0,0 +1,105 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"connect-text-bot/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	STORE_MEMORY = "memory"
+	STORE_BOLT   = "bolt"
+	STORE_REDIS  = "redis"
+)
+
+// время жизни состояния пользователя по умолчанию
+const DEFAULT_STATE_LIFETIME = 2 * time.Hour
+
+var ErrEntryNotFound = errors.New("entry not found")
+
+type (
+	// StateStore - хранилище состояний пользователей бота
+	StateStore interface {
+		// получить данные по ключу, если данных нет то ErrEntryNotFound
+		Get(key string) ([]byte, error)
+		// сохранить данные по ключу
+		Set(key string, data []byte) error
+		// удалить данные по ключу
+		Delete(key string) error
+		// получить список всех актуальных ключей
+		Keys() ([]string, error)
+		// закрыть хранилище
+		Close() error
+	}
+
+	// настройки хранилища состояний
+	StateStoreConfig struct {
+		// Тип хранилища: memory (по умолчанию), bolt или redis
+		Type string `yaml:"type"`
+		// Время жизни состояния пользователя, по умолчанию 2h
+		Lifetime time.Duration `yaml:"lifetime"`
+		// Путь к файлу базы (для bolt)
+		Path string `yaml:"path"`
+
+		// Адрес сервера host:port (для redis)
+		Addr     string `yaml:"addr"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		DB       int    `yaml:"db"`
+		// Префикс ключей, чтобы несколько ботов могли использовать один redis (для redis)
+		Prefix string `yaml:"prefix"`
+	}
+)
+
+// ConnectStateStore - создать хранилище состояний согласно настройкам
+func ConnectStateStore(cnf StateStoreConfig) StateStore {
+	if cnf.Lifetime <= 0 {
+		cnf.Lifetime = DEFAULT_STATE_LIFETIME
+	}
+
+	switch cnf.Type {
+	case "", STORE_MEMORY:
+		logger.Info("State store: memory")
+		return ConnectInMemoryCache(cnf.Lifetime)
+
+	case STORE_BOLT:
+		if cnf.Path == "" {
+			cnf.Path = "./state.db"
+		}
+		logger.Info("State store: bolt", cnf.Path)
+
+		store, err := ConnectBoltStore(cnf.Path, cnf.Lifetime)
+		if err != nil {
+			logger.Crit(err)
+		}
+		return store
+
+	case STORE_REDIS:
+		if cnf.Addr == "" {
+			cnf.Addr = "localhost:6379"
+		}
+		if cnf.Prefix == "" {
+			cnf.Prefix = "connect-text-bot:"
+		}
+		logger.Info("State store: redis", cnf.Addr)
+
+		store, err := ConnectRedisStore(cnf)
+		if err != nil {
+			logger.Crit(err)
+		}
+		return store
+
+	default:
+		logger.Crit("Unknown state store type:", cnf.Type)
+	}
+	return nil
+}
+
+func InjectStateStore(key string, store StateStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(key, store)
+	}
+}
@@ -0,0 +1,107 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+
+	"connect-text-bot/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// окно в течение которого повторное сообщение считается дублем
+const DEFAULT_WINDOW = 10 * time.Minute
+
+type (
+	// настройки отсеивания повторных доставок
+	Config struct {
+		// Сколько помнить полученные message_id, по умолчанию 10m
+		Window time.Duration `yaml:"window"`
+	}
+
+	// Filter - множество недавно полученных message_id. Хранится в памяти процесса:
+	// при нескольких репликах за балансировщиком повтор, доставленный на другую реплику, не отсеивается
+	Filter struct {
+		mu     sync.Mutex
+		seen   map[uuid.UUID]time.Time
+		window time.Duration
+
+		done chan struct{}
+	}
+)
+
+func New(cnf Config) *Filter {
+	if cnf.Window <= 0 {
+		cnf.Window = DEFAULT_WINDOW
+	}
+
+	f := &Filter{
+		seen:   make(map[uuid.UUID]time.Time),
+		window: cnf.Window,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(f.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f.cleanup()
+			case <-f.done:
+				return
+			}
+		}
+	}()
+
+	return f
+}
+
+// Close - остановить периодическую очистку
+func (f *Filter) Close() {
+	close(f.done)
+}
+
+// Seen - проверить получали ли сообщение недавно, если нет то запомнить его
+func (f *Filter) Seen(messageID uuid.UUID) bool {
+	if messageID == uuid.Nil {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if t, ok := f.seen[messageID]; ok && time.Since(t) < f.window {
+		metrics.DuplicateMessages.Inc()
+		return true
+	}
+	f.seen[messageID] = time.Now()
+	return false
+}
+
+// Forget - забыть сообщение, чтобы его повторная доставка была обработана
+func (f *Filter) Forget(messageID uuid.UUID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.seen, messageID)
+}
+
+// удалить устаревшие записи
+func (f *Filter) cleanup() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for id, t := range f.seen {
+		if time.Since(t) >= f.window {
+			delete(f.seen, id)
+		}
+	}
+}
+
+func Inject(key string, filter *Filter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(key, filter)
+	}
+}
@@ -0,0 +1,96 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestFilterSeen(t *testing.T) {
+	f := New(Config{Window: time.Hour})
+	defer f.Close()
+	id := uuid.New()
+
+	if f.Seen(id) {
+		t.Fatal("first delivery reported as duplicate")
+	}
+	if !f.Seen(id) {
+		t.Fatal("second delivery not reported as duplicate")
+	}
+	if f.Seen(uuid.New()) {
+		t.Fatal("other message reported as duplicate")
+	}
+	// сообщения без id не отсеиваются
+	if f.Seen(uuid.Nil) || f.Seen(uuid.Nil) {
+		t.Fatal("message without id reported as duplicate")
+	}
+}
+
+func TestFilterWindow(t *testing.T) {
+	f := New(Config{Window: 20 * time.Millisecond})
+	defer f.Close()
+	id := uuid.New()
+
+	f.Seen(id)
+	time.Sleep(30 * time.Millisecond)
+	if f.Seen(id) {
+		t.Fatal("delivery after window reported as duplicate")
+	}
+}
+
+func TestFilterCleanup(t *testing.T) {
+	f := &Filter{seen: make(map[uuid.UUID]time.Time), window: time.Minute}
+	old, fresh := uuid.New(), uuid.New()
+	f.seen[old] = time.Now().Add(-2 * time.Minute)
+	f.seen[fresh] = time.Now()
+
+	f.cleanup()
+	if _, ok := f.seen[old]; ok {
+		t.Error("expired message not removed")
+	}
+	if _, ok := f.seen[fresh]; !ok {
+		t.Error("fresh message removed")
+	}
+}
+
+func TestFilterForget(t *testing.T) {
+	f := New(Config{Window: time.Hour})
+	defer f.Close()
+	id := uuid.New()
+
+	f.Seen(id)
+	f.Forget(id)
+	if f.Seen(id) {
+		t.Fatal("forgotten message reported as duplicate")
+	}
+}
+
+func TestFilterPeriodicCleanup(t *testing.T) {
+	f := New(Config{Window: 10 * time.Millisecond})
+	f.Seen(uuid.New())
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		f.mu.Lock()
+		n := len(f.seen)
+		f.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired message not removed by periodic cleanup")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// после Close очистка остановлена
+	f.Close()
+	f.Seen(uuid.New())
+	time.Sleep(50 * time.Millisecond)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.seen) != 1 {
+		t.Fatal("cleanup still running after Close")
+	}
+}
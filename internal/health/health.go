@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DEFAULT_CACHE_TTL = 30 * time.Second
+	// сколько ждать ответа одной проверки
+	checkTimeout = 5 * time.Second
+)
+
+type (
+	// настройки проверок готовности
+	Config struct {
+		// Сколько хранить результат проверки внешних сервисов, по умолчанию 30s
+		CacheTTL time.Duration `yaml:"cache_ttl"`
+	}
+
+	// Check - проверка зависимости, nil если все в порядке
+	Check func(ctx context.Context) error
+
+	check struct {
+		name   string
+		cached bool
+		fn     Check
+
+		mu      sync.Mutex
+		err     error
+		checked time.Time
+	}
+
+	// Checker - набор проверок для /readyz
+	Checker struct {
+		ttl    time.Duration
+		checks []*check
+	}
+)
+
+func New(cnf Config) *Checker {
+	if cnf.CacheTTL <= 0 {
+		cnf.CacheTTL = DEFAULT_CACHE_TTL
+	}
+	return &Checker{ttl: cnf.CacheTTL}
+}
+
+// Add - добавить проверку, результат cached проверок переиспользуется в течение cache_ttl
+func (h *Checker) Add(name string, cached bool, fn Check) {
+	h.checks = append(h.checks, &check{name: name, cached: cached, fn: fn})
+}
+
+func (h *Checker) run(ctx context.Context, ch *check) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.cached && !ch.checked.IsZero() && time.Since(ch.checked) < h.ttl {
+		return ch.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	err := ch.fn(ctx)
+	// запрос отменен или проверка не уложилась в таймаут: результат не кешируем,
+	// следующий запрос проверит заново
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	ch.err = err
+	ch.checked = time.Now()
+	return err
+}
+
+// Run - выполнить все проверки параллельно
+func (h *Checker) Run(ctx context.Context) (ready bool, report map[string]string) {
+	report = make(map[string]string, len(h.checks))
+	ready = true
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, ch := range h.checks {
+		wg.Add(1)
+		go func(ch *check) {
+			defer wg.Done()
+
+			err := h.run(ctx, ch)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				ready = false
+				report[ch.name] = err.Error()
+			} else {
+				report[ch.name] = "ok"
+			}
+		}(ch)
+	}
+	wg.Wait()
+
+	return
+}
+
+// Healthz - процесс жив и отвечает на запросы
+func (h *Checker) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz - бот готов обрабатывать сообщения
+func (h *Checker) Readyz(c *gin.Context) {
+	ready, report := h.Run(c.Request.Context())
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "fail", "checks": report})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "checks": report})
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// проверка, которая считает вызовы и возвращает err
+func countingCheck(calls *atomic.Int32, err *error) Check {
+	return func(context.Context) error {
+		calls.Add(1)
+		return *err
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := New(Config{})
+	var failing error
+	h.Add("menu", false, func(context.Context) error { return nil })
+	h.Add("connect", false, func(context.Context) error { return failing })
+
+	app := gin.New()
+	app.GET("/readyz", h.Readyz)
+	app.GET("/healthz", h.Healthz)
+	readyz := func() (int, map[string]any) {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, body
+	}
+
+	if code, body := readyz(); code != http.StatusOK || body["status"] != "ok" {
+		t.Fatalf("readyz = %d %v", code, body)
+	}
+
+	failing = errors.New("connection refused")
+	code, body := readyz()
+	if code != http.StatusServiceUnavailable || body["status"] != "fail" {
+		t.Fatalf("readyz with failed check = %d %v", code, body)
+	}
+	checks := body["checks"].(map[string]any)
+	if checks["connect"] != "connection refused" || checks["menu"] != "ok" {
+		t.Errorf("checks = %v", checks)
+	}
+
+	// healthz не зависит от проверок
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz = %d", w.Code)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	h := New(Config{CacheTTL: 50 * time.Millisecond})
+	var cachedCalls, liveCalls atomic.Int32
+	var err error
+	h.Add("connect", true, countingCheck(&cachedCalls, &err))
+	h.Add("hooks", false, countingCheck(&liveCalls, &err))
+
+	ctx := context.Background()
+	h.Run(ctx)
+	err = errors.New("down")
+	// в пределах cache_ttl используется прежний результат внешней проверки
+	if ready, report := h.Run(ctx); ready || report["connect"] != "ok" {
+		t.Errorf("report = %v", report)
+	}
+	if cachedCalls.Load() != 1 || liveCalls.Load() != 2 {
+		t.Fatalf("calls: cached %d, live %d", cachedCalls.Load(), liveCalls.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, report := h.Run(ctx); report["connect"] != "down" {
+		t.Errorf("report after ttl = %v", report)
+	}
+	if cachedCalls.Load() != 2 {
+		t.Fatalf("cached check called %d times after ttl, want 2", cachedCalls.Load())
+	}
+}
+
+func TestCanceledCheckNotCached(t *testing.T) {
+	h := New(Config{CacheTTL: time.Hour})
+	var calls atomic.Int32
+	h.Add("connect", true, func(ctx context.Context) error {
+		calls.Add(1)
+		return ctx.Err()
+	})
+
+	// запрос /readyz прервали: результат не запоминается
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ready, _ := h.Run(canceled); ready {
+		t.Fatal("canceled check reported ready")
+	}
+
+	if ready, report := h.Run(context.Background()); !ready {
+		t.Fatalf("check after cancel not repeated: %v", report)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("check called %d times, want 2", calls.Load())
+	}
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// уровни, которых нет в slog
+const (
+	levelEvent = slog.Level(2)
+	levelCrit  = slog.Level(12)
+)
+
+type ctxKey struct{}
+
+var jsonLog *slog.Logger
+
+// пишет туда же куда и log, чтобы учитывались log.SetOutput
+type logWriter struct{}
+
+func (logWriter) Write(p []byte) (int, error) {
+	return log.Writer().Write(p)
+}
+
+func initJSON() {
+	jsonLog = slog.New(slog.NewJSONHandler(logWriter{}, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key != slog.LevelKey {
+				return a
+			}
+			switch a.Value.Any().(slog.Level) {
+			case levelEvent:
+				a.Value = slog.StringValue("EVENT")
+			case levelCrit:
+				a.Value = slog.StringValue("CRIT")
+			}
+			return a
+		},
+	}))
+}
+
+// IsJSON - логи пишутся в формате json
+func IsJSON() bool {
+	return jsonLog != nil
+}
+
+// With - добавить к контексту поля, которые попадут в каждую запись лога с этим контекстом.
+// Поле с уже добавленным ключом заменяется
+func With(ctx context.Context, args ...any) context.Context {
+	prev := fields(ctx)
+	attrs := make([]slog.Attr, 0, len(prev)+len(args)/2)
+
+	var added []slog.Attr
+	for i := 0; i+1 < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok {
+			continue
+		}
+		added = append(added, slog.Any(key, args[i+1]))
+	}
+
+next:
+	for _, a := range prev {
+		for _, b := range added {
+			if a.Key == b.Key {
+				continue next
+			}
+		}
+		attrs = append(attrs, a)
+	}
+	return context.WithValue(ctx, ctxKey{}, append(attrs, added...))
+}
+
+func fields(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+func InfoCtx(ctx context.Context, v ...interface{}) {
+	output(ctx, slog.LevelInfo, v...)
+}
+
+func WarningCtx(ctx context.Context, v ...interface{}) {
+	output(ctx, slog.LevelWarn, v...)
+}
+
+func DebugCtx(ctx context.Context, v ...interface{}) {
+	output(ctx, slog.LevelDebug, v...)
+}
+
+// Gin - журнал запросов к боту в формате json, вместо стандартного журнала gin
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		jsonLog.LogAttrs(c, slog.LevelInfo, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// параметры запроса, значения которых не пишутся в журнал
+var secretParams = []string{"token"}
+
+// GinText - стандартный журнал запросов gin, но без секретов из строки запроса
+func GinText(out io.Writer) gin.HandlerFunc {
+	return gin.LoggerWithConfig(gin.LoggerConfig{
+		Output: out,
+		Formatter: func(param gin.LogFormatterParams) string {
+			if param.Latency > time.Minute {
+				param.Latency = param.Latency.Truncate(time.Second)
+			}
+			return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+				param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+				param.StatusCode,
+				param.Latency,
+				param.ClientIP,
+				param.Method,
+				redactPath(param.Path),
+				param.ErrorMessage,
+			)
+		},
+	})
+}
+
+// redactPath - заменить значения секретных параметров в пути с запросом
+func redactPath(path string) string {
+	p, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// не разобрали - не пишем запрос совсем
+		return p + "?[REDACTED]"
+	}
+	redacted := false
+	for _, name := range secretParams {
+		if _, ok := query[name]; ok {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return path
+	}
+	return p + "?" + query.Encode()
+}
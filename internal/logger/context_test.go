@@ -0,0 +1,21 @@
+package logger
+
+import "testing"
+
+func TestRedactPath(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/connect-push/receive/", "/connect-push/receive/"},
+		{"/connect-push/receive/?token=secret", "/connect-push/receive/?token=REDACTED"},
+		{"/connect-push/receive/?a=1&token=secret", "/connect-push/receive/?a=1&token=REDACTED"},
+		{"/metrics?a=1", "/metrics?a=1"},
+		{"/connect-push/receive/?token=%zz", "/connect-push/receive/?[REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		if got := redactPath(tt.path); got != tt.want {
+			t.Errorf("redactPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -2,11 +2,14 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/fatih/color"
@@ -15,6 +18,8 @@ import (
 
 var (
 	isDebug = false
+	// каталог файлов лога, пустой если логи не сохраняются
+	logDirectory string
 
 	CritColor    = color.RGB(255, 0, 0).SprintFunc()
 	DebugColor   = color.RGB(255, 165, 0).SprintFunc()
@@ -24,13 +29,24 @@ var (
 
 type (
 	loggerConfig struct {
+		// Формат логов: text (по умолчанию) или json
+		Format string `yaml:"format"`
+
 		Logging *struct {
 			// Сохранять ли логи
 			Enabled bool `yaml:"enabled"`
 			// В какую папку сохранять по умолчанию "./log"
 			Directory string `yaml:"directory"`
-			// Формат даты и времени в имени файла
+			// Формат даты и времени в имени файла, при смене имени логи пишутся в новый файл
 			FilenameFormat string `yaml:"filename_format"`
+			// Максимальный размер файла в мегабайтах, 0 - без ограничения
+			MaxSize int `yaml:"max_size"`
+			// Сколько хранить старые файлы, 0 - бессрочно
+			MaxAge time.Duration `yaml:"max_age"`
+			// Сколько хранить старых файлов, 0 - без ограничения
+			MaxFiles int `yaml:"max_files"`
+			// Сжимать ли старые файлы в gzip
+			Compress bool `yaml:"compress"`
 		} `yaml:"logging"`
 
 		// Настройки цветов
@@ -51,7 +67,7 @@ type (
 	}
 )
 
-func InitLogger(debug bool, configPath *string) *os.File {
+func InitLogger(debug bool, configPath *string) *RotatingFile {
 	isDebug = debug
 	color.NoColor = true
 
@@ -74,8 +90,18 @@ func InitLogger(debug bool, configPath *string) *os.File {
 		return nil
 	}
 
+	switch cnf.Format {
+	case "", "text":
+	case "json":
+		log.SetPrefix("")
+		log.SetFlags(0)
+		initJSON()
+	default:
+		Warning("Неизвестный формат логов", cnf.Format, ", используется text")
+	}
+
 	// настраиваем цвета
-	if cnf.Color != nil && !cnf.Color.NoColor {
+	if cnf.Color != nil && !cnf.Color.NoColor && !IsJSON() {
 		color.NoColor = cnf.Color.NoColor
 
 		setColorCnf := func(cData colorConf, globColor *func(a ...interface{}) string) {
@@ -106,15 +132,22 @@ func InitLogger(debug bool, configPath *string) *os.File {
 			cnf.Logging.FilenameFormat = "app"
 		}
 
-		fileName := fmt.Sprintf("%s/%s.log", cnf.Logging.Directory, time.Now().Format(cnf.Logging.FilenameFormat))
-
-		logFile, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
+		logFile, err := OpenRotating(RotateOptions{
+			Directory:      cnf.Logging.Directory,
+			FilenameFormat: cnf.Logging.FilenameFormat,
+			Ext:            ".log",
+			MaxSize:        cnf.Logging.MaxSize,
+			MaxAge:         cnf.Logging.MaxAge,
+			MaxFiles:       cnf.Logging.MaxFiles,
+			Compress:       cnf.Logging.Compress,
+		})
 		if err != nil {
 			Warning("Ошибка связанная с файлом записи логов, в данный момент логи не сохраняются: ", err)
 			return nil
 		}
 		mw := io.MultiWriter(os.Stdout, logFile)
 		log.SetOutput(mw)
+		logDirectory = cnf.Logging.Directory
 
 		return logFile
 	}
@@ -122,38 +155,84 @@ func InitLogger(debug bool, configPath *string) *os.File {
 	return nil
 }
 
+// Directory - каталог файлов лога, пустой если логи не сохраняются
+func Directory() string {
+	return logDirectory
+}
+
 func Info(v ...interface{}) {
-	log.Print("[INFO] ", fmt.Sprintln(v...))
+	output(context.Background(), slog.LevelInfo, v...)
 }
 
 func Event(v ...interface{}) {
-	log.Print(EventColor("[Event] ", fmt.Sprintln(v...)))
+	output(context.Background(), levelEvent, v...)
 }
 
 func Warning(v ...interface{}) {
-	log.Print(WarningColor("[WARNING] ", fmt.Sprintln(v...)))
+	output(context.Background(), slog.LevelWarn, v...)
 }
 
 func Debug(v ...interface{}) {
-	if isDebug {
-		message := new(bytes.Buffer)
+	output(context.Background(), slog.LevelDebug, v...)
+}
 
-		for _, str := range v {
-			v, ok := str.(string)
-			if ok {
-				_, _ = fmt.Fprintf(message, "%s ", v)
-			} else {
-				s, _ := json.MarshalIndent(str, "", " ")
-				_, _ = fmt.Fprintf(message, "%s ", string(s))
-			}
-		}
+func Crit(v ...interface{}) {
+	if IsJSON() {
+		jsonLog.Log(context.Background(), levelCrit, strings.TrimSpace(fmt.Sprintln(v...)))
+	} else {
+		log.Printf(CritColor("Critical error: %s"), v)
+	}
+	time.Sleep(5 * time.Second)
+	os.Exit(1)
+}
+
+// output - записать сообщение, в формате json вместе с полями из ctx
+func output(ctx context.Context, level slog.Level, v ...interface{}) {
+	if level == slog.LevelDebug && !isDebug {
+		return
+	}
+
+	var message string
+	if level == slog.LevelDebug {
+		message = debugMessage(v)
+	} else {
+		message = fmt.Sprintln(v...)
+	}
+
+	if IsJSON() {
+		jsonLog.LogAttrs(ctx, level, strings.TrimSpace(message), fields(ctx)...)
+		return
+	}
 
+	switch level {
+	case slog.LevelDebug:
 		log.Print(DebugColor("[DEBUG] ", message))
+	case levelEvent:
+		log.Print(EventColor("[Event] ", message))
+	case slog.LevelWarn:
+		log.Print(WarningColor("[WARNING] ", message))
+	default:
+		log.Print("[INFO] ", message)
 	}
 }
 
-func Crit(v ...interface{}) {
-	log.Printf(CritColor("Critical error: %s"), v)
-	time.Sleep(5 * time.Second)
-	os.Exit(1)
+// строки выводятся как есть, остальные значения в виде json
+func debugMessage(v []interface{}) string {
+	message := new(bytes.Buffer)
+
+	for _, str := range v {
+		v, ok := str.(string)
+		if ok {
+			_, _ = fmt.Fprintf(message, "%s ", v)
+		} else {
+			var s []byte
+			if IsJSON() {
+				s, _ = json.Marshal(str)
+			} else {
+				s, _ = json.MarshalIndent(str, "", " ")
+			}
+			_, _ = fmt.Fprintf(message, "%s ", string(s))
+		}
+	}
+	return message.String()
 }
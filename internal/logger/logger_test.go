@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// перенаправить лог в буфер на время теста
+func captureLog(t *testing.T, asJSON bool) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	prevFlags, prevPrefix := log.Flags(), log.Prefix()
+	log.SetOutput(buf)
+	log.SetFlags(0)
+	log.SetPrefix("")
+	if asJSON {
+		initJSON()
+	}
+	t.Cleanup(func() {
+		jsonLog = nil
+		isDebug = false
+		log.SetOutput(os.Stderr)
+		log.SetFlags(prevFlags)
+		log.SetPrefix(prevPrefix)
+	})
+	return buf
+}
+
+// записи лога в формате json
+func jsonRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("not a json record %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestJSONOutput(t *testing.T) {
+	buf := captureLog(t, true)
+
+	Info("сообщение", 42)
+	Warning("предупреждение")
+	Event("событие")
+	Debug("не выводится без debug")
+	isDebug = true
+	Debug("отладка", map[string]int{"a": 1})
+
+	records := jsonRecords(t, buf)
+	if len(records) != 4 {
+		t.Fatalf("records = %v", records)
+	}
+	want := []struct{ level, msg string }{
+		{"INFO", "сообщение 42"},
+		{"WARN", "предупреждение"},
+		{"EVENT", "событие"},
+		{"DEBUG", `отладка {"a":1}`},
+	}
+	for i, w := range want {
+		if records[i]["level"] != w.level || records[i]["msg"] != w.msg {
+			t.Errorf("record %d = %v, want level %s msg %q", i, records[i], w.level, w.msg)
+		}
+		if _, ok := records[i]["time"]; !ok {
+			t.Errorf("record %d without time: %v", i, records[i])
+		}
+	}
+}
+
+func TestWithFields(t *testing.T) {
+	buf := captureLog(t, true)
+
+	ctx := With(context.Background(), "line_id", "l1", "user_id", "u1")
+	// ключ не строка и ключ без значения пропускаются
+	ctx = With(ctx, "user_id", "u2", 1, "x", "message_id")
+	InfoCtx(ctx, "обработка")
+	WarningCtx(context.Background(), "без полей")
+
+	records := jsonRecords(t, buf)
+	if len(records) != 2 {
+		t.Fatalf("records = %v", records)
+	}
+	if records[0]["line_id"] != "l1" || records[0]["user_id"] != "u2" || records[0]["message_id"] != nil {
+		t.Errorf("fields = %v", records[0])
+	}
+	if records[1]["line_id"] != nil {
+		t.Errorf("record without ctx has fields: %v", records[1])
+	}
+}
+
+func TestTextOutput(t *testing.T) {
+	buf := captureLog(t, false)
+
+	InfoCtx(With(context.Background(), "user_id", "u1"), "текст")
+	Warning("предупреждение")
+
+	got := buf.String()
+	if got != "[INFO] текст\n[WARNING] предупреждение\n" {
+		t.Errorf("text log = %q", got)
+	}
+}
@@ -0,0 +1,282 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// суффикс файла, переименованного при превышении MaxSize
+const backupFormat = "-20060102T150405.000"
+
+type (
+	// RotateOptions - настройки RotatingFile
+	RotateOptions struct {
+		// Каталог с файлами, создается если его нет
+		Directory string
+		// Формат даты и времени в имени файла
+		FilenameFormat string
+		// Расширение файлов, например ".log", обязательно
+		Ext string
+		// Максимальный размер файла в мегабайтах, 0 - без ограничения
+		MaxSize int
+		// Сколько хранить старые файлы, 0 - бессрочно
+		MaxAge time.Duration
+		// Сколько хранить старых файлов, 0 - без ограничения
+		MaxFiles int
+		// Сжимать старые файлы в gzip
+		Compress bool
+	}
+
+	// RotatingFile - файл, который переключается на новый при смене имени по FilenameFormat
+	// или превышении MaxSize. Старые файлы сжимаются и удаляются по MaxAge и MaxFiles
+	RotatingFile struct {
+		mu sync.Mutex
+
+		dir      string
+		format   string
+		ext      string
+		maxSize  int64
+		maxAge   time.Duration
+		maxFiles int
+		compress bool
+
+		file *os.File
+		name string
+		size int64
+
+		// сжатие и удаление старых файлов выполняются по одному
+		cleanupMu sync.Mutex
+	}
+)
+
+// OpenRotating - открыть файл для текущего времени и удалить устаревшие
+func OpenRotating(opts RotateOptions) (*RotatingFile, error) {
+	// по расширению отличаем свои файлы от чужих, без него удалялись бы все файлы каталога
+	if opts.Ext == "" {
+		return nil, fmt.Errorf("не указано расширение файлов в каталоге %s", opts.Directory)
+	}
+
+	r := &RotatingFile{
+		dir:      opts.Directory,
+		format:   opts.FilenameFormat,
+		ext:      opts.Ext,
+		maxSize:  int64(opts.MaxSize) << 20,
+		maxAge:   opts.MaxAge,
+		maxFiles: opts.MaxFiles,
+		compress: opts.Compress,
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := r.open(r.filename(time.Now())); err != nil {
+		return nil, err
+	}
+
+	// файлы прошлых запусков тоже подчищаем
+	go r.cleanup(r.name)
+	return r, nil
+}
+
+func (r *RotatingFile) filename(t time.Time) string {
+	return filepath.Join(r.dir, t.Format(r.format)+r.ext)
+}
+
+func (r *RotatingFile) open(name string) error {
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	r.file, r.name, r.size = file, name, info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	if r == nil {
+		return len(p), nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if name := r.filename(now); name != r.name {
+		// наступил новый период filename_format, предыдущий файл больше не пишется
+		prev := r.name
+		_ = r.file.Close()
+		if err := r.open(name); err != nil {
+			return 0, err
+		}
+		go r.rotated(prev, r.name)
+	} else if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		_ = r.file.Close()
+
+		backup := strings.TrimSuffix(r.name, r.ext) + now.Format(backupFormat) + r.ext
+		if err := os.Rename(r.name, backup); err != nil {
+			fmt.Fprintln(os.Stderr, "Error while rotate log file:", err)
+			backup = ""
+		}
+		if err := r.open(r.name); err != nil {
+			return 0, err
+		}
+		if backup != "" {
+			go r.rotated(backup, r.name)
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) Close() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// rotated - сжать файл, в который больше не пишем, и удалить лишние
+func (r *RotatingFile) rotated(name, current string) {
+	r.cleanupMu.Lock()
+	defer r.cleanupMu.Unlock()
+
+	if r.compress {
+		if err := compressFile(name); err != nil {
+			fmt.Fprintln(os.Stderr, "Error while compress log file:", err)
+		}
+	}
+	r.removeOld(current)
+}
+
+func (r *RotatingFile) cleanup(current string) {
+	r.cleanupMu.Lock()
+	defer r.cleanupMu.Unlock()
+	r.removeOld(current)
+}
+
+// removeOld - удалить файлы старше max_age и сверх max_files, кроме текущего
+func (r *RotatingFile) removeOld(current string) {
+	if r.maxAge <= 0 && r.maxFiles <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error while read log directory:", err)
+		return
+	}
+
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []logFile
+	for _, e := range entries {
+		path := filepath.Join(r.dir, e.Name())
+		if e.IsDir() || path == current || !r.owns(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{path: path, modTime: info.ModTime()})
+	}
+
+	// сначала новые
+	slices.SortFunc(files, func(a, b logFile) int {
+		return b.modTime.Compare(a.modTime)
+	})
+
+	for i, f := range files {
+		if (r.maxFiles > 0 && i >= r.maxFiles) || (r.maxAge > 0 && time.Since(f.modTime) > r.maxAge) {
+			if err := os.Remove(f.path); err != nil {
+				fmt.Fprintln(os.Stderr, "Error while remove old log file:", err)
+			}
+		}
+	}
+}
+
+// owns - файл создан этим RotatingFile: имя по FilenameFormat, возможно с суффиксом
+// переименования по размеру, затем расширение и возможно .gz
+func (r *RotatingFile) owns(name string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	base, ok := strings.CutSuffix(name, r.ext)
+	if !ok {
+		return false
+	}
+	if _, err := time.Parse(r.format, base); err == nil {
+		return true
+	}
+
+	if len(base) <= len(backupFormat) {
+		return false
+	}
+	base, suffix := base[:len(base)-len(backupFormat)], base[len(base)-len(backupFormat):]
+	if _, err := time.Parse(backupFormat, suffix); err != nil {
+		return false
+	}
+	_, err := time.Parse(r.format, base)
+	return err == nil
+}
+
+// compressFile - заменить файл на name.gz
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	// пишем во временный файл, чтобы не оставить обрезанный архив
+	tmp := name + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(name)
+	zw.ModTime = info.ModTime()
+
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, name+".gz"); err != nil {
+		return err
+	}
+	// сохраняем время, чтобы max_age считался от последней записи в лог
+	_ = os.Chtimes(name+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(name)
+}
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// touch - создать файл с временем изменения age назад
+func touch(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("x"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenRotatingRequiresExt(t *testing.T) {
+	if _, err := OpenRotating(RotateOptions{Directory: t.TempDir(), FilenameFormat: "app"}); err == nil {
+		t.Fatal("OpenRotating without Ext succeeded")
+	}
+}
+
+func TestRotatingFileOwns(t *testing.T) {
+	r := &RotatingFile{format: "app-2006-01-02", ext: ".log"}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app-2024-05-01.log", true},
+		{"app-2024-05-01.log.gz", true},
+		{"app-2024-05-01-20240501T101112.123.log", true},
+		{"app-2024-05-01-20240501T101112.123.log.gz", true},
+		{"app-2024-05-01.jsonl", false},
+		{"notes.log", false},
+		{"app-2024-05-01-backup.log", false},
+		{"app-2024-05-01.log.bak", false},
+	}
+	for _, tt := range tests {
+		if got := r.owns(tt.name); got != tt.want {
+			t.Errorf("owns(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	r, err := OpenRotating(RotateOptions{Directory: dir, FilenameFormat: "app", Ext: ".log", MaxSize: 1, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	chunk := bytes.Repeat([]byte("a"), 600<<10)
+	for i := 0; i < 2; i++ {
+		if _, err := r.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// сжатие идет в фоне
+	deadline := time.Now().Add(5 * time.Second)
+	var names []string
+	for time.Now().Before(deadline) {
+		names = listDir(t, dir)
+		if len(names) == 2 && slices.ContainsFunc(names, func(n string) bool { return strings.HasSuffix(n, ".log.gz") }) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !slices.Contains(names, "app.log") || len(names) != 2 {
+		t.Fatalf("files after rotation: %v", names)
+	}
+	for _, n := range names {
+		if n != "app.log" && !r.owns(n) {
+			t.Errorf("backup %s does not match FilenameFormat", n)
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(chunk)) {
+		t.Errorf("current file size = %d, want %d", info.Size(), len(chunk))
+	}
+}
+
+func TestRemoveOldKeepsForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"2024-05-01.log",
+		"2024-05-02.log.gz",
+		"2024-05-03-20240503T101112.123.log",
+		"2024-05-04.log",
+		"notes.log",
+		"2024-05-01.jsonl",
+		"readme.txt",
+	} {
+		touch(t, filepath.Join(dir, name), 48*time.Hour)
+	}
+	touch(t, filepath.Join(dir, "2024-05-05.log"), time.Hour)
+
+	r := &RotatingFile{dir: dir, format: "2006-01-02", ext: ".log", maxAge: 24 * time.Hour}
+	r.cleanup(filepath.Join(dir, "2024-05-04.log"))
+
+	want := []string{"2024-05-01.jsonl", "2024-05-04.log", "2024-05-05.log", "notes.log", "readme.txt"}
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("files after cleanup: %v, want %v", got, want)
+	}
+}
+
+func TestRemoveOldMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	for i, name := range []string{"2024-05-01.log.gz", "2024-05-02.log.gz", "2024-05-03.log.gz", "2024-05-04.log"} {
+		touch(t, filepath.Join(dir, name), time.Duration(4-i)*time.Hour)
+	}
+
+	r := &RotatingFile{dir: dir, format: "2006-01-02", ext: ".log", maxFiles: 2}
+	r.cleanup(filepath.Join(dir, "2024-05-04.log"))
+
+	// текущий файл в лимит не входит
+	want := []string{"2024-05-02.log.gz", "2024-05-03.log.gz", "2024-05-04.log"}
+	if got := listDir(t, dir); !slices.Equal(got, want) {
+		t.Fatalf("files after cleanup: %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "connect_text_bot"
+
+var (
+	// полученные сообщения по типу messages.MessageType
+	MessagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_received_total",
+		Help:      "Received messages by message_type.",
+	}, []string{"type"})
+
+	// повторные доставки сообщений, которые были пропущены
+	DuplicateMessages = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_messages_total",
+		Help:      "Redelivered messages skipped by message_id.",
+	})
+
+	// отклоненные запросы на адрес хука
+	RejectedHooks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rejected_hooks_total",
+		Help:      "Rejected hook requests by reason.",
+	}, []string{"reason"})
+
+	// нажатия на кнопки
+	ButtonClicks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "button_clicks_total",
+		Help:      "Button clicks by menu and button id.",
+	}, []string{"menu", "button"})
+
+	// ответы из базы знаний: hit - найден, miss - не найден
+	QnaResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qna_responses_total",
+		Help:      "Knowledge base lookups by result.",
+	}, []string{"result"})
+
+	// регистрация заявок: created - успешно, failed - с ошибкой
+	Tickets = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tickets_total",
+		Help:      "Service desk tickets by result.",
+	}, []string{"result"})
+
+	// выполнение команд exec_button по коду завершения
+	ExecButton = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exec_button_total",
+		Help:      "exec_button commands by exit code.",
+	}, []string{"exit_code"})
+
+	// длительность запросов к API 1С-Коннект
+	InvokeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "connect_request_duration_seconds",
+		Help:      "1C-Connect API request latency by endpoint and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "endpoint", "status"})
+
+	// повторы запросов к API 1С-Коннект после временных ошибок
+	InvokeRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connect_request_retries_total",
+		Help:      "1C-Connect API request retries by endpoint.",
+	}, []string{"method", "endpoint"})
+
+	// запросы к API 1С-Коннект отклоненные без отправки из-за circuit breaker
+	CircuitRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "connect_circuit_rejected_total",
+		Help:      "1C-Connect API requests rejected by open circuit breaker.",
+	}, []string{"method", "endpoint"})
+
+	// состояние circuit breaker: 0 - закрыт, 1 - открыт, 2 - пробный запрос
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connect_circuit_breaker_state",
+		Help:      "1C-Connect API circuit breaker state by endpoint: 0 closed, 1 open, 2 half-open.",
+	}, []string{"endpoint"})
+)
+
+var reUUID = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+// Endpoint - заменить id в адресе метода чтобы не плодить метки
+func Endpoint(methodUrl string) string {
+	return reUUID.ReplaceAllString(methodUrl, ":id")
+}
+
+// Handler - отдать метрики в формате Prometheus
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
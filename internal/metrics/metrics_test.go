@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEndpoint(t *testing.T) {
+	got := Endpoint("/v1/line/9f1c3b5e-2d4a-4c6b-8e7f-0a1b2c3d4e5f/user/9F1C3B5E-2D4A-4C6B-8E7F-0A1B2C3D4E5F/")
+	if want := "/v1/line/:id/user/:id/"; got != want {
+		t.Errorf("Endpoint() = %q, want %q", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.GET("/metrics", Handler())
+
+	RejectedHooks.WithLabelValues("token").Inc()
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `connect_text_bot_rejected_hooks_total{reason="token"}`) {
+		t.Errorf("counter not exported:\n%s", w.Body)
+	}
+}
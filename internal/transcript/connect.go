@@ -0,0 +1,76 @@
+package transcript
+
+import (
+	"context"
+
+	"connect-text-bot/internal/connect/client"
+	"connect-text-bot/internal/connect/requests"
+
+	"github.com/google/uuid"
+)
+
+// recordingAPI - ConnectAPI, который записывает в архив отправленное пользователю
+type recordingAPI struct {
+	client.ConnectAPI
+
+	archive *Archive
+	lineID  uuid.UUID
+}
+
+// Wrap - записывать в архив действия бота через api, если архив выключен то возвращает api
+func Wrap(api client.ConnectAPI, a *Archive, lineID uuid.UUID) client.ConnectAPI {
+	if a == nil {
+		return api
+	}
+	return recordingAPI{ConnectAPI: api, archive: a, lineID: lineID}
+}
+
+// записываем только успешные действия
+func (r recordingAPI) out(err error, userID uuid.UUID, e Entry) error {
+	if err == nil {
+		e.LineID, e.UserID, e.Direction = r.lineID, userID, OUT
+		r.archive.Record(e)
+	}
+	return err
+}
+
+func (r recordingAPI) Start(ctx context.Context, userID uuid.UUID) error {
+	return r.out(r.ConnectAPI.Start(ctx, userID), userID, Entry{Action: "drop_keyboard"})
+}
+
+func (r recordingAPI) Send(ctx context.Context, userID uuid.UUID, text string, keyboard *[][]requests.KeyboardKey) error {
+	err := r.ConnectAPI.Send(ctx, userID, text, keyboard)
+	return r.out(err, userID, Entry{Action: "text", Text: text, Keyboard: keyboardTexts(keyboard)})
+}
+
+func (r recordingAPI) SendFile(ctx context.Context, userID uuid.UUID, isImage bool, fileName string, filePath string, comment *string, keyboard *[][]requests.KeyboardKey) error {
+	err := r.ConnectAPI.SendFile(ctx, userID, isImage, fileName, filePath, comment, keyboard)
+
+	e := Entry{Action: "file", File: fileName, Keyboard: keyboardTexts(keyboard)}
+	if comment != nil {
+		e.Text = *comment
+	}
+	return r.out(err, userID, e)
+}
+
+func (r recordingAPI) DropKeyboard(ctx context.Context, userID uuid.UUID) error {
+	return r.out(r.ConnectAPI.DropKeyboard(ctx, userID), userID, Entry{Action: "drop_keyboard"})
+}
+
+func (r recordingAPI) CloseTreatment(ctx context.Context, userID uuid.UUID) error {
+	return r.out(r.ConnectAPI.CloseTreatment(ctx, userID), userID, Entry{Action: "close"})
+}
+
+func (r recordingAPI) RerouteTreatment(ctx context.Context, userID uuid.UUID) error {
+	return r.out(r.ConnectAPI.RerouteTreatment(ctx, userID), userID, Entry{Action: "redirect"})
+}
+
+func (r recordingAPI) Reroute(ctx context.Context, userID, toLineID uuid.UUID, quote string) error {
+	err := r.ConnectAPI.Reroute(ctx, userID, toLineID, quote)
+	return r.out(err, userID, Entry{Action: "reroute", ID: &toLineID, Text: quote})
+}
+
+func (r recordingAPI) AppointSpec(ctx context.Context, userID uuid.UUID, specID *uuid.UUID, appointSpec uuid.UUID) error {
+	err := r.ConnectAPI.AppointSpec(ctx, userID, specID, appointSpec)
+	return r.out(err, userID, Entry{Action: "appoint_spec", ID: &appointSpec})
+}
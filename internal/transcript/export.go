@@ -0,0 +1,122 @@
+package transcript
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Filter - какую переписку выгрузить
+type Filter struct {
+	UserID uuid.UUID
+	// uuid.Nil - все линии
+	LineID uuid.UUID
+	// [From, To), нулевое значение - без ограничения
+	From time.Time
+	To   time.Time
+}
+
+func (f Filter) match(e Entry) bool {
+	return e.UserID == f.UserID &&
+		(f.LineID == uuid.Nil || e.LineID == f.LineID) &&
+		(f.From.IsZero() || !e.Time.Before(f.From)) &&
+		(f.To.IsZero() || e.Time.Before(f.To))
+}
+
+// Read - переписка пользователя из архива в порядке времени
+func Read(cnf Config, f Filter) ([]Entry, error) {
+	cnf.setDefaults()
+
+	dirEntries, err := os.ReadDir(cnf.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, de := range dirEntries {
+		name := de.Name()
+		if de.IsDir() || !(strings.HasSuffix(name, fileExt) || strings.HasSuffix(name, fileExt+".gz")) {
+			continue
+		}
+		// в файл, который последний раз менялся до начала периода, нужные записи попасть не могли
+		if info, err := de.Info(); err == nil && !f.From.IsZero() && info.ModTime().Before(f.From) {
+			continue
+		}
+
+		found, err := readFile(filepath.Join(cnf.Directory, name), f)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return a.Time.Compare(b.Time)
+	})
+	return entries, nil
+}
+
+func readFile(path string, f Filter) (entries []Entry, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		// файл могли сжать или удалить пока читали каталог
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		// оборванную при аварийной остановке строку пропускаем
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if f.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// ParseTime - время в формате RFC3339 или дата 2006-01-02.
+// Для конца периода (end) дата означает конец дня
+func ParseTime(v string, end bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
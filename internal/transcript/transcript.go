@@ -0,0 +1,212 @@
+// Package transcript - архив переписки бота с пользователями в файлах JSON Lines, по файлу на день
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/connect/requests"
+	"connect-text-bot/internal/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	DEFAULT_DIRECTORY = "./transcripts"
+
+	fileFormat = "2006-01-02"
+	fileExt    = ".jsonl"
+)
+
+// направление сообщения
+const (
+	IN  = "in"
+	OUT = "out"
+)
+
+type (
+	// настройки архива переписки
+	Config struct {
+		// Записывать ли переписку
+		Enabled bool `yaml:"enabled"`
+		// Каталог для файлов, по умолчанию ./transcripts
+		Directory string `yaml:"directory"`
+		// Сколько хранить переписку, 0 - бессрочно
+		MaxAge time.Duration `yaml:"max_age"`
+		// Сжимать файлы прошлых дней в gzip
+		Compress bool `yaml:"compress"`
+	}
+
+	// Entry - запись архива: сообщение пользователя или действие бота
+	Entry struct {
+		Time      time.Time `json:"time"`
+		LineID    uuid.UUID `json:"line_id"`
+		UserID    uuid.UUID `json:"user_id"`
+		Direction string    `json:"direction"`
+		// message - входящее сообщение, для исходящих: text, file, drop_keyboard, close, redirect, reroute, appoint_spec
+		Action string `json:"action"`
+		// тип входящего сообщения
+		MessageType messages.MessageType `json:"message_type,omitempty"`
+		MessageID   *uuid.UUID           `json:"message_id,omitempty"`
+		// текст сообщения или подпись к файлу
+		Text string `json:"text,omitempty"`
+		File string `json:"file,omitempty"`
+		// тексты кнопок клавиатуры
+		Keyboard []string `json:"keyboard,omitempty"`
+		// reroute - линия, appoint_spec - специалист
+		ID *uuid.UUID `json:"id,omitempty"`
+	}
+
+	// Archive - запись переписки, nil если архив выключен
+	Archive struct {
+		file *logger.RotatingFile
+	}
+)
+
+func (cnf *Config) setDefaults() {
+	if cnf.Directory == "" {
+		cnf.Directory = DEFAULT_DIRECTORY
+	}
+}
+
+// в каталоге лога переписка перемешалась бы с логами, а очистка одного трогала бы файлы другого
+func (cnf *Config) validate() error {
+	logDir := logger.Directory()
+	if logDir == "" {
+		return nil
+	}
+	dir, err := filepath.Abs(cnf.Directory)
+	if err != nil {
+		return err
+	}
+	if logDir, err = filepath.Abs(logDir); err != nil {
+		return err
+	}
+	if dir == logDir {
+		return fmt.Errorf("transcript.directory %s совпадает с каталогом логов", cnf.Directory)
+	}
+	return nil
+}
+
+// New - открыть архив, если он выключен то возвращает nil
+func New(cnf Config) (*Archive, error) {
+	if !cnf.Enabled {
+		return nil, nil
+	}
+	cnf.setDefaults()
+	if err := cnf.validate(); err != nil {
+		return nil, err
+	}
+
+	file, err := logger.OpenRotating(logger.RotateOptions{
+		Directory:      cnf.Directory,
+		FilenameFormat: fileFormat,
+		Ext:            fileExt,
+		MaxAge:         cnf.MaxAge,
+		Compress:       cnf.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Archive{file: file}, nil
+}
+
+// Record - записать в архив, время проставляется если не задано
+func (a *Archive) Record(e Entry) {
+	if a == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		logger.Warning("Error while encode transcript entry", err)
+		return
+	}
+
+	if _, err := a.file.Write(append(b, '\n')); err != nil {
+		logger.Warning("Error while write transcript", err)
+	}
+}
+
+// Inbound - записать сообщение пользователя
+func (a *Archive) Inbound(msg messages.Message) {
+	e := Entry{
+		LineID:      msg.LineID,
+		UserID:      msg.UserID,
+		Direction:   IN,
+		Action:      "message",
+		MessageType: msg.MessageType,
+		Text:        msg.Text,
+	}
+	if msg.MessageID != uuid.Nil {
+		e.MessageID = &msg.MessageID
+	}
+	a.Record(e)
+}
+
+func (a *Archive) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+func keyboardTexts(keyboard *[][]requests.KeyboardKey) (texts []string) {
+	if keyboard == nil {
+		return nil
+	}
+	for _, row := range *keyboard {
+		for _, key := range row {
+			texts = append(texts, key.Text)
+		}
+	}
+	return
+}
+
+func (e Entry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Local().Format("2006-01-02 15:04:05"))
+	if e.Direction == IN {
+		b.WriteString(" <- ")
+	} else {
+		b.WriteString(" -> ")
+	}
+
+	switch e.Action {
+	case "message":
+		if e.Text != "" {
+			b.WriteString(e.Text)
+		} else {
+			fmt.Fprintf(&b, "[событие %d]", e.MessageType)
+		}
+	case "text":
+		b.WriteString(e.Text)
+	case "file":
+		fmt.Fprintf(&b, "[файл %s] %s", e.File, e.Text)
+	default:
+		b.WriteString("[" + e.Action)
+		if e.ID != nil {
+			b.WriteString(" " + e.ID.String())
+		}
+		b.WriteString("]")
+	}
+
+	if len(e.Keyboard) != 0 {
+		fmt.Fprintf(&b, " %q", e.Keyboard)
+	}
+	return b.String()
+}
+
+func Inject(key string, a *Archive) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(key, a)
+	}
+}
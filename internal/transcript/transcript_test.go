@@ -0,0 +1,128 @@
+package transcript
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"connect-text-bot/internal/connect/connecttest"
+	"connect-text-bot/internal/connect/messages"
+	"connect-text-bot/internal/connect/requests"
+	"connect-text-bot/internal/logger"
+
+	"github.com/google/uuid"
+)
+
+func TestArchive(t *testing.T) {
+	cnf := Config{Enabled: true, Directory: t.TempDir()}
+	a, err := New(cnf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	lineID, otherLine := uuid.New(), uuid.New()
+	user, other := uuid.New(), uuid.New()
+	fake := connecttest.New(lineID)
+	api := Wrap(fake, a, lineID)
+
+	a.Inbound(messages.Message{LineID: lineID, UserID: user, MessageID: uuid.New(), MessageType: messages.MESSAGE_TEXT, Text: "привет"})
+	_ = api.Send(ctx, user, "Здравствуйте", &[][]requests.KeyboardKey{{{ID: "1", Text: "Меню"}}})
+	spec := uuid.New()
+	_ = api.AppointSpec(ctx, user, nil, spec)
+	// неудачная отправка не записывается
+	fake.Errors["Send"] = errors.New("send failed")
+	_ = api.Send(ctx, user, "не дошло", nil)
+	// переписка другого пользователя и другой линии
+	a.Inbound(messages.Message{LineID: lineID, UserID: other, Text: "чужое"})
+	a.Inbound(messages.Message{LineID: otherLine, UserID: user, Text: "другая линия"})
+
+	entries, err := Read(cnf, Filter{UserID: user, LineID: lineID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Direction+" "+e.Action+" "+e.Text)
+	}
+	want := []string{"in message привет", "out text Здравствуйте", "out appoint_spec "}
+	if !slices.Equal(got, want) {
+		t.Fatalf("entries = %q, want %q", got, want)
+	}
+	if !slices.Equal(entries[1].Keyboard, []string{"Меню"}) || entries[2].ID == nil || *entries[2].ID != spec {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	// все линии
+	if entries, _ := Read(cnf, Filter{UserID: user}); len(entries) != 4 {
+		t.Errorf("entries of all lines = %d, want 4", len(entries))
+	}
+	// период после записи
+	if entries, _ := Read(cnf, Filter{UserID: user, From: time.Now().Add(time.Hour)}); len(entries) != 0 {
+		t.Errorf("entries after period start = %v", entries)
+	}
+}
+
+func TestDisabledArchive(t *testing.T) {
+	a, err := New(Config{})
+	if a != nil || err != nil {
+		t.Fatalf("New(disabled) = %v, %v", a, err)
+	}
+	// выключенный архив ничего не делает
+	a.Record(Entry{Text: "x"})
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := connecttest.New(uuid.New())
+	if api := Wrap(fake, a, uuid.New()); api != fake {
+		t.Fatal("disabled archive wraps api")
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		v    string
+		end  bool
+		want time.Time
+	}{
+		{"", false, time.Time{}},
+		{"2024-03-01", false, day},
+		{"2024-03-01", true, day.AddDate(0, 0, 1)},
+		{"2024-03-01T10:00:00Z", true, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.v, tt.end)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q, %v) = %v, %v, want %v", tt.v, tt.end, got, err, tt.want)
+		}
+	}
+	if _, err := ParseTime("01.03.2024", false); err == nil {
+		t.Error("invalid date parsed")
+	}
+}
+
+func TestNewRejectsLogDirectory(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "logger.yml")
+	if err := os.WriteFile(logFile, []byte("logging:\n  enabled: true\n  directory: "+dir+"/log\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	logger.InitLogger(false, &logFile).Close()
+
+	if _, err := New(Config{Enabled: true, Directory: dir + "/log/"}); err == nil {
+		t.Fatal("New with log directory succeeded")
+	}
+
+	a, err := New(Config{Enabled: true, Directory: dir + "/transcripts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Close()
+}
@@ -1,6 +1,10 @@
 package us
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/hooklift/gowsdl/soap"
 )
@@ -9,6 +13,28 @@ type UsServer struct {
 	Addr string `yaml:"addr"`
 }
 
+// Ping - проверка доступности SOAP сервиса
+func Ping(us UsServer, login, password string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, us.Addr+"?wsdl", nil)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(login, password)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("SOAP сервис ответил %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
 func Inject(us UsServer, login, password string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		soapcl := soap.NewClient(
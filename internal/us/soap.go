@@ -4,13 +4,36 @@ import (
 	"context"
 
 	"connect-text-bot/internal/database"
+	"connect-text-bot/internal/metrics"
 
 	"github.com/google/uuid"
 	"github.com/hooklift/gowsdl/soap"
 )
 
+// TicketCreator - регистрация заявок в УС
+type TicketCreator interface {
+	CreateTicket(ctx context.Context, userID, lineID uuid.UUID, ticket database.Ticket) (content map[string]string, err error)
+}
+
+// SoapTicketCreator - регистрация заявок через SOAP сервис УС
+type SoapTicketCreator struct {
+	Client *soap.Client
+}
+
+func (s SoapTicketCreator) CreateTicket(ctx context.Context, userID, lineID uuid.UUID, ticket database.Ticket) (map[string]string, error) {
+	return CreateTicket(ctx, s.Client, userID, lineID, ticket)
+}
+
 // Создание заявки на обслуживание
 func CreateTicket(ctx context.Context, soapcl *soap.Client, userID, lineID uuid.UUID, ticket database.Ticket) (content map[string]string, err error) {
+	defer func() {
+		if err != nil {
+			metrics.Tickets.WithLabelValues("failed").Inc()
+		} else {
+			metrics.Tickets.WithLabelValues("created").Inc()
+		}
+	}()
+
 	service := NewPartnerWebAPI2PortType(soapcl)
 
 	serviceRequestAdd, err := service.ServiceRequestAddContext(
@@ -0,0 +1,194 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"connect-text-bot/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DEFAULT_COUNT         = 16
+	DEFAULT_QUEUE_SIZE    = 1024
+	DEFAULT_DRAIN_TIMEOUT = 30 * time.Second
+
+	// сколько ждать завершения отмененных задач при остановке
+	cancelGrace = 5 * time.Second
+)
+
+var (
+	// очередь заполнена, задачу стоит повторить позже
+	ErrQueueFull = errors.New("worker queue is full")
+	// пул остановлен и не принимает задачи
+	ErrClosed = errors.New("worker pool is closed")
+)
+
+type (
+	// настройки обработчиков входящих сообщений
+	Config struct {
+		// Количество одновременно обрабатываемых диалогов
+		Count int `yaml:"count"`
+		// Сколько сообщений может ожидать обработки, при превышении новые сообщения отклоняются
+		QueueSize int `yaml:"queue_size"`
+		// Сколько ждать обработки оставшихся сообщений при остановке бота
+		DrainTimeout time.Duration `yaml:"drain_timeout"`
+	}
+
+	// Task - задача пула, ctx отменяется если пул остановлен не дождавшись ее выполнения
+	Task func(ctx context.Context)
+
+	// Pool - ограниченный пул обработчиков, задачи с одинаковым ключом выполняются строго по очереди
+	Pool struct {
+		mu sync.Mutex
+		// ожидающие задачи по ключу, первая в очереди выполняется в данный момент
+		queues  map[string][]Task
+		pending int
+		maxSize int
+		closed  bool
+
+		// ключи, задачи которых готовы к выполнению
+		ready chan string
+
+		// контекст задач, не зависит от http запроса в котором задача поставлена
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		tasks   sync.WaitGroup
+		workers sync.WaitGroup
+	}
+)
+
+func New(cnf Config) *Pool {
+	if cnf.Count <= 0 {
+		cnf.Count = DEFAULT_COUNT
+	}
+	if cnf.QueueSize <= 0 {
+		cnf.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := &Pool{
+		queues:  make(map[string][]Task),
+		maxSize: cnf.QueueSize,
+		// в ready не может оказаться больше ключей чем ожидающих задач
+		ready: make(chan string, cnf.QueueSize),
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	p.workers.Add(cnf.Count)
+	for range cnf.Count {
+		go p.work()
+	}
+
+	return p
+}
+
+// Submit - поставить задачу в очередь ключа, не блокирует вызывающего
+func (p *Pool) Submit(key string, task Task) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	if p.pending >= p.maxSize {
+		return ErrQueueFull
+	}
+
+	p.pending++
+	p.tasks.Add(1)
+	p.queues[key] = append(p.queues[key], task)
+
+	// если по ключу ничего не выполняется, то отдаем его обработчикам
+	if len(p.queues[key]) == 1 {
+		p.ready <- key
+	}
+	return nil
+}
+
+func (p *Pool) work() {
+	defer p.workers.Done()
+
+	for key := range p.ready {
+		p.mu.Lock()
+		task := p.queues[key][0]
+		p.mu.Unlock()
+
+		p.run(task)
+
+		p.mu.Lock()
+		p.queues[key] = p.queues[key][1:]
+		p.pending--
+		if len(p.queues[key]) == 0 {
+			delete(p.queues, key)
+		} else {
+			p.ready <- key
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pool) run(task Task) {
+	defer p.tasks.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Warning("Panic while processing task:", r)
+		}
+	}()
+	task(p.ctx)
+}
+
+// Pending - количество задач ожидающих выполнения
+func (p *Pool) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pending
+}
+
+// Shutdown - перестать принимать задачи и дождаться выполнения уже принятых.
+// Если ctx истек раньше, то выполняемые задачи отменяются
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.tasks.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		p.cancel()
+
+		// оставшиеся задачи выполнятся с отмененным контекстом и завершатся сразу
+		select {
+		case <-drained:
+		case <-time.After(cancelGrace):
+			logger.Warning("Tasks did not finish after cancel")
+			return err
+		}
+	}
+	p.cancel()
+
+	close(p.ready)
+	p.workers.Wait()
+	return err
+}
+
+func Inject(key string, pool *Pool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(key, pool)
+	}
+}
@@ -0,0 +1,145 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolKeyOrder(t *testing.T) {
+	p := New(Config{Count: 4, QueueSize: 1000})
+
+	var mu sync.Mutex
+	got := make(map[string][]int)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint("user", i%3)
+		n := i
+		err := p.Submit(key, func(context.Context) {
+			// разная длительность задач не должна менять порядок внутри ключа
+			time.Sleep(time.Duration(n%4) * time.Millisecond)
+			mu.Lock()
+			got[key] = append(got[key], n)
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for key, tasks := range got {
+		if !slices.IsSorted(tasks) {
+			t.Errorf("tasks of %s out of order: %v", key, tasks)
+		}
+	}
+	if n := len(got["user0"]) + len(got["user1"]) + len(got["user2"]); n != 100 {
+		t.Errorf("executed %d tasks, want 100", n)
+	}
+}
+
+func TestPoolKeysRunInParallel(t *testing.T) {
+	p := New(Config{Count: 2})
+	defer p.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	started := make(chan string, 2)
+	for _, key := range []string{"a", "b"} {
+		key := key
+		_ = p.Submit(key, func(context.Context) {
+			started <- key
+			<-release
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("tasks with different keys did not run in parallel")
+		}
+	}
+	close(release)
+}
+
+func TestPoolQueueFull(t *testing.T) {
+	p := New(Config{Count: 1, QueueSize: 2})
+
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		if err := p.Submit("a", func(context.Context) { <-release }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Submit("a", func(context.Context) {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Submit over queue size = %v, want ErrQueueFull", err)
+	}
+
+	close(release)
+	_ = p.Shutdown(context.Background())
+}
+
+func TestPoolShutdownDrains(t *testing.T) {
+	p := New(Config{Count: 1})
+
+	var done atomic.Int32
+	for i := 0; i < 5; i++ {
+		_ = p.Submit("a", func(context.Context) {
+			time.Sleep(5 * time.Millisecond)
+			done.Add(1)
+		})
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if done.Load() != 5 {
+		t.Fatalf("done %d tasks before shutdown returned, want 5", done.Load())
+	}
+	if err := p.Submit("a", func(context.Context) {}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Submit after shutdown = %v, want ErrClosed", err)
+	}
+}
+
+func TestPoolShutdownTimeoutCancelsTasks(t *testing.T) {
+	p := New(Config{Count: 1})
+
+	var canceled atomic.Int32
+	for i := 0; i < 3; i++ {
+		_ = p.Submit("a", func(ctx context.Context) {
+			<-ctx.Done()
+			canceled.Add(1)
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want DeadlineExceeded", err)
+	}
+	// ожидавшие задачи тоже выполнены, но с отмененным контекстом
+	if canceled.Load() != 3 {
+		t.Fatalf("canceled %d tasks, want 3", canceled.Load())
+	}
+}
+
+func TestPoolRecoversPanic(t *testing.T) {
+	p := New(Config{Count: 1})
+
+	var after atomic.Bool
+	_ = p.Submit("a", func(context.Context) { panic("boom") })
+	_ = p.Submit("a", func(context.Context) { after.Store(true) })
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !after.Load() {
+		t.Fatal("task after panic was not executed")
+	}
+}